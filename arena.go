@@ -0,0 +1,254 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// arenaAlign is the granularity of arena blocks. Every block, including
+	// the padding block skipped at the end of the ring, is at least as large
+	// as a block header.
+	arenaAlign = 16
+
+	// reclaimBit marks the head of an arena while one goroutine is reclaiming
+	// the block at head, so that no other one reclaims it concurrently.
+	reclaimBit = int64(1) << 62
+)
+
+// arenaBlock is the header in front of every block in an Arena.
+//
+// state is 2*pos while the block starting at position pos is in use, and
+// 2*pos+1 once it has been freed. Space that is not allocated is kept zeroed,
+// so a header whose allocation is still in progress never looks freed.
+type arenaBlock struct {
+	state int64
+	size  int64 // Size of the block including this header
+}
+
+// Handle refers to bytes allocated from an Arena.
+//
+// A Handle is a plain pair of integers rather than a pointer, so it can be sent
+// through a Channel[Handle] and is meaningful in every process that maps the
+// arena, wherever the mapping lies.
+type Handle struct {
+	Off uint32 // Offset of the bytes from the start of the arena's data area
+	Len uint32 // Number of bytes
+}
+
+// Arena is a garbage collection-free allocator for variable-length byte
+// strings that operates on a user-provided memory block, like Channel[T].
+//
+// Allocations are carved from a ring: Alloc claims space at the tail with a
+// single compare-and-swap, and Free marks a block as freed. Space is reclaimed
+// once every block before it has been freed, so the arena works best when
+// blocks are freed in roughly the order they were allocated, as when the
+// handles travel through a channel. Alloc, Bytes and Free may be called
+// concurrently from multiple goroutines.
+//
+// Example usage:
+//
+//	size := xxchan.SizeofArena(4096)
+//	buf := make([]byte, size)
+//	a := xxchan.MakeArena(unsafe.Pointer(&buf[0]), 4096)
+//	h, ok := a.Alloc(5)
+//	copy(a.Bytes(h), "hello")
+//	a.Free(h)
+type Arena struct {
+	header
+	head int64 // Oldest position not yet reclaimed, with reclaimBit set while reclaiming
+	tail int64 // Next position to allocate from
+	cap  int64 // Size of the data area, a multiple of arenaAlign; the struct is one too
+}
+
+// arenaCap rounds n up to the data area size used for an arena of n bytes.
+func arenaCap(n int) int {
+	// The second bound only matters where int is 32 bits wide.
+	if n < 0 || uint64(n) > math.MaxUint32-arenaAlign || n > math.MaxInt-maxAlign {
+		panic(ErrInvalidCapacity)
+	}
+	return alignUp(n, arenaAlign)
+}
+
+// SizeofArena calculates the total memory size required for an Arena that can
+// hold n bytes of blocks, headers included.
+//
+// Every allocation of k bytes takes alignUp(k, 16)+16 bytes of the arena, and
+// an allocation that would run past the end of the ring skips the remainder.
+// n must not exceed 4 GiB, since Handle offsets are 32 bits wide.
+//
+// Parameters:
+//   - n: The desired size of the arena's data area in bytes
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the arena
+func SizeofArena(n int) int {
+	return int(unsafe.Sizeof(Arena{})) + arenaCap(n)
+}
+
+// MakeArena initializes a new Arena using a pre-allocated memory block of at
+// least SizeofArena(n) bytes, aligned to 16 bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The size of the arena's data area in bytes
+//
+// Returns:
+//   - A pointer to the initialized Arena
+//
+// The safety requirements of Make apply unchanged.
+func MakeArena(ptr unsafe.Pointer, n int) *Arena {
+	a := (*Arena)(ptr)
+	a.cap = int64(arenaCap(n))
+	a.head = 0
+	a.tail = 0
+	clear(a.data())
+	a.header.init(layoutArena, 1, 1, fingerprintOf[byte]())
+	return a
+}
+
+// AttachArena is like Attach for a block initialized by MakeArena.
+func AttachArena(ptr unsafe.Pointer) (*Arena, error) {
+	a := (*Arena)(ptr)
+	if err := a.header.check(layoutArena, 1, 1, fingerprintOf[byte]()); err != nil {
+		return nil, err
+	}
+	if a.cap < 0 || a.cap > math.MaxUint32 || a.cap%arenaAlign != 0 {
+		return nil, fmt.Errorf("%w: invalid arena size %d", ErrNotChannel, a.cap)
+	}
+	return a, nil
+}
+
+// data returns the arena's data area, located immediately after the Arena
+// struct in memory.
+func (a *Arena) data() []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(a), unsafe.Sizeof(*a))), a.cap)
+}
+
+// block returns the header of the block at offset off of the data area.
+func (a *Arena) block(off int64) *arenaBlock {
+	return (*arenaBlock)(unsafe.Add(unsafe.Pointer(a), int64(unsafe.Sizeof(*a))+off))
+}
+
+// Alloc allocates n bytes from the arena without blocking.
+//
+// The bytes are not zeroed; use Bytes to fill them in before handing the
+// handle to another goroutine.
+//
+// Parameters:
+//   - n: Number of bytes to allocate
+//
+// Returns:
+//   - A handle to the allocated bytes
+//   - false if the arena has no room for n contiguous bytes
+func (a *Arena) Alloc(n int) (h Handle, ok bool) {
+	if a == nil || n < 0 || int64(n) >= a.cap {
+		return Handle{}, false // A block never fits, as its header takes space as well
+	}
+	size := int64(alignUp(n, arenaAlign)) + int64(unsafe.Sizeof(arenaBlock{}))
+	for {
+		head := atomic.LoadInt64(&a.head) &^ reclaimBit
+		tail := atomic.LoadInt64(&a.tail)
+		off := tail % a.cap
+		pad := int64(0)
+		if off+size > a.cap {
+			pad = a.cap - off
+		}
+		if tail+pad+size-head > a.cap {
+			return Handle{}, false
+		}
+		if !atomic.CompareAndSwapInt64(&a.tail, tail, tail+pad+size) {
+			continue
+		}
+		start := tail + pad
+		b := a.block(start % a.cap)
+		b.size = size
+		atomic.StoreInt64(&b.state, 2*start)
+		if pad > 0 {
+			// The remainder of the ring is skipped by an already freed block.
+			p := a.block(off)
+			p.size = pad
+			atomic.StoreInt64(&p.state, 2*tail+1)
+			a.reclaim()
+		}
+		return Handle{Off: uint32(start%a.cap) + uint32(unsafe.Sizeof(arenaBlock{})), Len: uint32(n)}, true
+	}
+}
+
+// Bytes returns the bytes h refers to, which stay valid until h is freed.
+func (a *Arena) Bytes(h Handle) []byte {
+	return a.data()[h.Off : h.Off+h.Len : h.Off+h.Len]
+}
+
+// Free releases the bytes h refers to. The handle, and any slice returned by
+// Bytes for it, must not be used afterwards.
+func (a *Arena) Free(h Handle) {
+	b := a.block(int64(h.Off) - int64(unsafe.Sizeof(arenaBlock{})))
+	atomic.StoreInt64(&b.state, atomic.LoadInt64(&b.state)|1)
+	a.reclaim()
+}
+
+// reclaim advances head over freed blocks, zeroing them so that they can be
+// allocated again.
+//
+// Whoever frees a block calls reclaim afterwards. Together with reclaim
+// checking the block at head after every advance, this guarantees that a
+// block freed while another goroutine reclaims is never left behind.
+func (a *Arena) reclaim() {
+	for {
+		head := atomic.LoadInt64(&a.head)
+		if head&reclaimBit != 0 {
+			return
+		}
+		b := a.block(head % a.cap)
+		if atomic.LoadInt64(&b.state) != 2*head+1 {
+			return
+		}
+		if !atomic.CompareAndSwapInt64(&a.head, head, head|reclaimBit) {
+			continue
+		}
+		off := head % a.cap
+		size := b.size
+		clear(a.data()[off+int64(unsafe.Sizeof(arenaBlock{})) : off+size])
+		b.size = 0
+		atomic.StoreInt64(&b.state, 0)
+		atomic.StoreInt64(&a.head, head+size)
+	}
+}
+
+// Len returns the number of bytes currently allocated or awaiting
+// reclamation, headers and padding included.
+func (a *Arena) Len() int {
+	if a == nil {
+		return 0
+	}
+	head := atomic.LoadInt64(&a.head) &^ reclaimBit
+	return int(atomic.LoadInt64(&a.tail) - head)
+}
+
+// Cap returns the size of the arena's data area in bytes.
+func (a *Arena) Cap() int {
+	if a == nil {
+		return 0
+	}
+	return int(a.cap)
+}
@@ -0,0 +1,226 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestArena(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 128
+	ptr := alloc(uint(xxchan.SizeofArena(n)))
+	t.Cleanup(func() { free(ptr) })
+
+	a := xxchan.MakeArena(ptr, n)
+	assert.Equal(n, a.Cap())
+
+	// 40 bytes of data take 48 plus a 16-byte header.
+	h1, ok := a.Alloc(40)
+	assert.True(ok)
+	copy(a.Bytes(h1), "first")
+	h2, ok := a.Alloc(40)
+	assert.True(ok)
+	copy(a.Bytes(h2), "second")
+	assert.Equal(128, a.Len())
+	_, ok = a.Alloc(0)
+	assert.False(ok)
+
+	// Freeing out of order reclaims nothing until the oldest block goes.
+	a.Free(h2)
+	assert.Equal(128, a.Len())
+	a.Free(h1)
+	assert.Equal(0, a.Len())
+
+	h3, ok := a.Alloc(8)
+	assert.True(ok)
+	h4, ok := a.Alloc(40)
+	assert.True(ok)
+	a.Free(h3)
+
+	// The next block would straddle the end of the ring, so it needs the
+	// skipped remainder as well and only fits once h4 is gone.
+	_, ok = a.Alloc(40)
+	assert.False(ok)
+	a.Free(h4)
+	h5, ok := a.Alloc(40)
+	assert.True(ok)
+	assert.Equal(uint32(16), h5.Off)
+	assert.Equal(40, len(a.Bytes(h5)))
+	a.Free(h5)
+	assert.Equal(0, a.Len())
+
+	_, ok = a.Alloc(n + 1)
+	assert.False(ok)
+}
+
+func TestArenaZero(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofArena(0)))
+	t.Cleanup(func() { free(ptr) })
+
+	a := xxchan.MakeArena(ptr, 0)
+	assert.Equal(0, a.Cap())
+	_, ok := a.Alloc(0)
+	assert.False(ok)
+	assert.Equal(0, a.Len())
+}
+
+func TestAttachArena(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofArenaChannel(4, 256)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeArenaChannel(ptr, 4, 256)
+	assert.True(ch.PushString("hello"))
+
+	attached, err := xxchan.AttachArenaChannel(ptr)
+	assert.NoError(err)
+	b, ok := attached.PopCopy(nil)
+	assert.True(ok)
+	assert.Equal("hello", string(b))
+
+	a, err := xxchan.AttachArena(unsafe.Pointer(ch.Arena()))
+	assert.NoError(err)
+	assert.Equal(256, a.Cap())
+
+	_, err = xxchan.AttachArena(ptr)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+	_, err = xxchan.Attach[xxchan.Handle](ptr)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+	_, err = xxchan.AttachArenaChannel(unsafe.Pointer(ch.Handles()))
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+
+	// A channel without handle slots attaches like any other.
+	empty := alloc(uint(xxchan.SizeofArenaChannel(0, 256)))
+	t.Cleanup(func() { free(empty) })
+	xxchan.MakeArenaChannel(empty, 0, 256)
+	attached, err = xxchan.AttachArenaChannel(empty)
+	assert.NoError(err)
+	assert.Equal(0, attached.Handles().Cap())
+	assert.False(attached.PushString("hello"))
+}
+
+func TestArenaChannel(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofArenaChannel(4, 256)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeArenaChannel(ptr, 4, 256)
+	assert.Equal(4, ch.Cap())
+
+	assert.True(ch.PushString("hello"))
+	assert.True(ch.PushBytes([]byte("world")))
+	assert.True(ch.PushString(""))
+	assert.Equal(3, ch.Len())
+
+	var seen string
+	assert.True(ch.PopView(func(p []byte) { seen = string(p) }))
+	assert.Equal("hello", seen)
+	b, ok := ch.PopCopy([]byte("hello, "))
+	assert.True(ok)
+	assert.Equal("hello, world", string(b))
+	b, ok = ch.PopCopy(nil)
+	assert.True(ok)
+	assert.Empty(b)
+	_, ok = ch.PopCopy(nil)
+	assert.False(ok)
+	assert.Equal(0, ch.Arena().Len())
+
+	// A value larger than the arena is refused without taking a slot.
+	assert.False(ch.PushBytes(make([]byte, 512)))
+	assert.Equal(0, ch.Len())
+
+	// A full handle channel gives the arena space back.
+	for i := range 4 {
+		assert.True(ch.PushString(strconv.Itoa(i)))
+	}
+	assert.False(ch.PushString("overflow"))
+	for i := range 4 {
+		b, ok = ch.PopCopy(b[:0])
+		assert.True(ok)
+		assert.Equal(strconv.Itoa(i), string(b))
+	}
+	assert.Equal(0, ch.Arena().Len())
+
+	assert.NoError(ch.Close())
+	assert.True(ch.Closed())
+	assert.False(ch.PushString("late"))
+}
+
+func TestArenaChannelConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofArenaChannel(16, 512)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeArenaChannel(ptr, 16, 512)
+
+	producers, perProducer := 4, 500
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				// Vary the length so blocks wrap at different offsets.
+				s := strconv.Itoa(p) + ":" + strconv.Itoa(i) + ":" + string(make([]byte, i%37))
+				for !ch.PushString(s) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	counts := make([]int, producers)
+	var buf []byte
+	for got := 0; got < producers*perProducer; {
+		var ok bool
+		buf, ok = ch.PopCopy(buf[:0])
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		got++
+		p, err := strconv.Atoi(string(buf[:1]))
+		assert.NoError(err)
+		counts[p]++
+	}
+	wg.Wait()
+
+	for _, c := range counts {
+		assert.Equal(perProducer, c)
+	}
+	assert.Equal(0, ch.Arena().Len())
+}
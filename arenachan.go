@@ -0,0 +1,209 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// ArenaChannel is a channel of variable-length byte strings that keeps both
+// the queue and the bytes off the garbage-collected heap.
+//
+// It combines, in one user-provided memory block, a Channel[Handle] carrying
+// the handles and an Arena holding the bytes they refer to. PushBytes and
+// PushString copy a value into the arena and queue its handle; PopView and
+// PopCopy dequeue a handle, hand out its bytes and free them, so the arena
+// space is reclaimed as values are consumed. Since the block holds no Go
+// pointers, it may also be shared with other processes.
+//
+// All operations are safe for concurrent use, as on Channel[T].
+//
+// Example usage:
+//
+//	size := xxchan.SizeofArenaChannel(64, 4096)
+//	buf := make([]byte, size)
+//	ch := xxchan.MakeArenaChannel(unsafe.Pointer(&buf[0]), 64, 4096)
+//	ch.PushString("hello")
+//	s, ok := ch.PopCopy(nil)
+type ArenaChannel struct {
+	header
+	arenaOff int64 // Offset of the Arena from the start of the block
+}
+
+// arenaOffset returns the offset of the Arena in an ArenaChannel block with n
+// handle slots.
+func arenaOffset(n int) int {
+	return alignUp(int(unsafe.Sizeof(ArenaChannel{}))+Sizeof[Handle](n), arenaAlign)
+}
+
+// SizeofArenaChannel calculates the total memory size required for an
+// ArenaChannel that queues up to n values totalling about bytes bytes.
+//
+// Parameters:
+//   - n: The maximum number of queued values
+//   - bytes: The size of the arena holding the values; see SizeofArena
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the channel
+func SizeofArenaChannel(n, bytes int) int {
+	return arenaOffset(n) + SizeofArena(bytes)
+}
+
+// MakeArenaChannel initializes a new ArenaChannel using a pre-allocated memory
+// block of at least SizeofArenaChannel(n, bytes) bytes, aligned to 16 bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The maximum number of queued values
+//   - bytes: The size of the arena holding the values
+//
+// Returns:
+//   - A pointer to the initialized ArenaChannel
+//
+// The safety requirements of Make apply unchanged.
+func MakeArenaChannel(ptr unsafe.Pointer, n, bytes int) *ArenaChannel {
+	c := (*ArenaChannel)(ptr)
+	c.arenaOff = int64(arenaOffset(n))
+	Make[Handle](unsafe.Add(ptr, unsafe.Sizeof(*c)), n)
+	MakeArena(unsafe.Add(ptr, c.arenaOff), bytes)
+	c.header.init(layoutArenaChannel, unsafe.Sizeof(Handle{}), unsafe.Alignof(Handle{}), fingerprintOf[Handle]())
+	return c
+}
+
+// AttachArenaChannel is like Attach for a block initialized by
+// MakeArenaChannel. It checks the headers of the handle channel and of the
+// arena inside the block as well.
+func AttachArenaChannel(ptr unsafe.Pointer) (*ArenaChannel, error) {
+	c := (*ArenaChannel)(ptr)
+	if err := c.header.check(layoutArenaChannel, unsafe.Sizeof(Handle{}), unsafe.Alignof(Handle{}), fingerprintOf[Handle]()); err != nil {
+		return nil, err
+	}
+	handles, err := Attach[Handle](unsafe.Add(ptr, unsafe.Sizeof(*c)))
+	if err != nil {
+		return nil, err
+	}
+	if want := arenaOffset(handles.Cap()); c.arenaOff != int64(want) {
+		return nil, fmt.Errorf("%w: arena at offset %d, want %d", ErrNotChannel, c.arenaOff, want)
+	}
+	if _, err := AttachArena(unsafe.Add(ptr, c.arenaOff)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Handles returns the channel carrying the handles of the queued values.
+func (c *ArenaChannel) Handles() *Channel[Handle] {
+	return (*Channel[Handle])(unsafe.Add(unsafe.Pointer(c), unsafe.Sizeof(*c)))
+}
+
+// Arena returns the arena holding the bytes of the queued values.
+func (c *ArenaChannel) Arena() *Arena {
+	return (*Arena)(unsafe.Add(unsafe.Pointer(c), c.arenaOff))
+}
+
+// PushBytes copies p into the arena and queues it without blocking.
+//
+// Returns:
+//   - true if the value was successfully added
+//   - false if the channel or the arena is full, or the channel is closed
+func (c *ArenaChannel) PushBytes(p []byte) (ok bool) {
+	a := c.Arena()
+	h, ok := a.Alloc(len(p))
+	if !ok {
+		return false
+	}
+	copy(a.Bytes(h), p)
+	if !c.Handles().Push(h) {
+		a.Free(h)
+		return false
+	}
+	return true
+}
+
+// PushString copies s into the arena and queues it without blocking.
+//
+// Returns:
+//   - true if the value was successfully added
+//   - false if the channel or the arena is full, or the channel is closed
+func (c *ArenaChannel) PushString(s string) (ok bool) {
+	a := c.Arena()
+	h, ok := a.Alloc(len(s))
+	if !ok {
+		return false
+	}
+	copy(a.Bytes(h), s)
+	if !c.Handles().Push(h) {
+		a.Free(h)
+		return false
+	}
+	return true
+}
+
+// PopView dequeues the oldest value and passes its bytes to fn without
+// copying them. The bytes are freed when fn returns, so fn must not retain p.
+//
+// Returns:
+//   - true if a value was dequeued and passed to fn
+//   - false if the channel is empty
+func (c *ArenaChannel) PopView(fn func(p []byte)) (ok bool) {
+	h, ok := c.Handles().Pop()
+	if !ok {
+		return false
+	}
+	a := c.Arena()
+	defer a.Free(h)
+	fn(a.Bytes(h))
+	return true
+}
+
+// PopCopy dequeues the oldest value and appends its bytes to dst.
+//
+// Returns:
+//   - The extended slice
+//   - false if the channel is empty, in which case dst is returned unchanged
+func (c *ArenaChannel) PopCopy(dst []byte) (b []byte, ok bool) {
+	h, ok := c.Handles().Pop()
+	if !ok {
+		return dst, false
+	}
+	a := c.Arena()
+	dst = append(dst, a.Bytes(h)...)
+	a.Free(h)
+	return dst, true
+}
+
+// Close closes the channel; see Channel.Close.
+func (c *ArenaChannel) Close() error {
+	return c.Handles().Close()
+}
+
+// Closed reports whether Close has been called.
+func (c *ArenaChannel) Closed() bool {
+	return c.Handles().Closed()
+}
+
+// Len returns the number of values currently queued.
+func (c *ArenaChannel) Len() int {
+	return c.Handles().Len()
+}
+
+// Cap returns the maximum number of values the channel can queue.
+func (c *ArenaChannel) Cap() int {
+	return c.Handles().Cap()
+}
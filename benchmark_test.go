@@ -18,10 +18,13 @@
 package xxchan_test
 
 import (
+	"context"
 	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 
-	"github.com/smasher164/mem"
 	"go.yuchanns.xyz/xxchan"
 )
 
@@ -32,8 +35,8 @@ var benchmarkSizes = []int{10, 100, 1000, 10000}
 func benchmarkXXChanPush(b *testing.B) {
 	for _, size := range benchmarkSizes {
 		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
-			ptr := mem.Alloc(uint(xxchan.Sizeof[int](size)))
-			defer mem.Free(ptr)
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
 
 			ch := xxchan.Make[int](ptr, size)
 
@@ -77,8 +80,8 @@ func benchmarkBuiltinChanPush(b *testing.B) {
 func benchmarkXXChanPop(b *testing.B) {
 	for _, size := range benchmarkSizes {
 		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
-			ptr := mem.Alloc(uint(xxchan.Sizeof[int](size)))
-			defer mem.Free(ptr)
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
 
 			ch := xxchan.Make[int](ptr, size)
 
@@ -137,8 +140,8 @@ func benchmarkBuiltinChanPop(b *testing.B) {
 func benchmarkXXChanMixed(b *testing.B) {
 	for _, size := range benchmarkSizes {
 		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
-			ptr := mem.Alloc(uint(xxchan.Sizeof[int](size)))
-			defer mem.Free(ptr)
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
 
 			ch := xxchan.Make[int](ptr, size)
 
@@ -189,10 +192,10 @@ func benchmarkXXChanCreation(b *testing.B) {
 			b.ReportAllocs()
 
 			for b.Loop() {
-				ptr := mem.Alloc(uint(xxchan.Sizeof[int](size)))
+				ptr := alloc(uint(xxchan.Sizeof[int](size)))
 				ch := xxchan.Make[int](ptr, size)
 				_ = ch
-				mem.Free(ptr)
+				free(ptr)
 			}
 		})
 	}
@@ -216,8 +219,8 @@ func benchmarkXXChanConcurrent(b *testing.B) {
 	const size = 1000
 	const numWorkers = 4
 
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int](size)))
-	defer mem.Free(ptr)
+	ptr := alloc(uint(xxchan.Sizeof[int](size)))
+	defer free(ptr)
 
 	ch := xxchan.Make[int](ptr, size)
 
@@ -263,6 +266,342 @@ func benchmarkBuiltinChanConcurrent(b *testing.B) {
 	})
 }
 
+// benchmarkSPSCMixed benchmarks mixed Push/Pop operations for the SPSC variant
+func benchmarkSPSCMixed(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
+			ptr := alloc(uint(xxchan.SizeofSPSC[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.MakeSPSC[int](ptr, size)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; b.Loop(); i++ {
+				if i%2 == 0 {
+					ch.Push(i)
+				} else {
+					ch.Pop()
+				}
+			}
+		})
+	}
+}
+
+// benchmarkMPSCMixed benchmarks mixed Push/Pop operations for the MPSC variant
+func benchmarkMPSCMixed(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
+			ptr := alloc(uint(xxchan.SizeofMPSC[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.MakeMPSC[int](ptr, size)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; b.Loop(); i++ {
+				if i%2 == 0 {
+					ch.Push(i)
+				} else {
+					ch.Pop()
+				}
+			}
+		})
+	}
+}
+
+// benchmarkXXChanBatch benchmarks moving values through Channel with PushN/PopN
+func benchmarkXXChanBatch(b *testing.B) {
+	const batch = 64
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.Make[int](ptr, size)
+			src := make([]int, min(batch, size))
+			dst := make([]int, min(batch, size))
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for b.Loop() {
+				ch.PushN(src)
+				ch.PopN(dst)
+			}
+			b.ReportMetric(float64(b.N*len(src))/b.Elapsed().Seconds(), "values/s")
+		})
+	}
+}
+
+// waitChannel is the blocking subset of the method set shared by every channel
+// flavour, used to run the same topology benchmark against each of them.
+type waitChannel interface {
+	PushWait(ctx context.Context, val int) error
+	PopWait(ctx context.Context) (int, error)
+}
+
+// builtinChan adapts a built-in channel to waitChannel.
+type builtinChan chan int
+
+func (c builtinChan) PushWait(ctx context.Context, val int) error {
+	c <- val
+	return nil
+}
+
+func (c builtinChan) PopWait(ctx context.Context) (int, error) {
+	return <-c, nil
+}
+
+// benchmarkTopology moves b.N values from the given number of producer
+// goroutines to a single consumer through ch.
+func benchmarkTopology(b *testing.B, ch waitChannel, producers int) {
+	ctx := context.Background()
+	wg := &sync.WaitGroup{}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for p := range producers {
+		n := b.N / producers
+		if p == 0 {
+			n += b.N % producers
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				_ = ch.PushWait(ctx, i)
+			}
+		}()
+	}
+	for range b.N {
+		_, _ = ch.PopWait(ctx)
+	}
+	wg.Wait()
+}
+
+// benchmarkXXChanPipeline benchmarks one producer and one consumer over Channel
+func benchmarkXXChanPipeline(b *testing.B) {
+	const size = 1000
+	ptr := alloc(uint(xxchan.Sizeof[int](size)))
+	defer free(ptr)
+
+	benchmarkTopology(b, xxchan.Make[int](ptr, size), 1)
+}
+
+// benchmarkSPSCPipeline benchmarks one producer and one consumer over SPSC
+func benchmarkSPSCPipeline(b *testing.B) {
+	const size = 1000
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](size)))
+	defer free(ptr)
+
+	benchmarkTopology(b, xxchan.MakeSPSC[int](ptr, size), 1)
+}
+
+// benchmarkSPSCSplit benchmarks one producer and one consumer over SPSC that
+// retry non-blocking Push and Pop instead of sleeping, so that with two or
+// more processors the run is dominated by the cache lines the two sides share.
+func benchmarkSPSCSplit(b *testing.B) {
+	const size = 1024
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](size)))
+	defer free(ptr)
+
+	ch := xxchan.MakeSPSC[int](ptr, size)
+	wg := &sync.WaitGroup{}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < b.N; {
+			if ch.Push(i) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < b.N; {
+		if _, ok := ch.Pop(); ok {
+			i++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+}
+
+func benchmarkBuiltinChanPipeline(b *testing.B) {
+	const size = 1000
+	benchmarkTopology(b, make(builtinChan, size), 1)
+}
+
+// benchmarkXXChanFanIn benchmarks several producers and one consumer over Channel
+func benchmarkXXChanFanIn(b *testing.B) {
+	const size = 1000
+	const numProducers = 4
+	ptr := alloc(uint(xxchan.Sizeof[int](size)))
+	defer free(ptr)
+
+	benchmarkTopology(b, xxchan.Make[int](ptr, size), numProducers)
+}
+
+// benchmarkMPSCFanIn benchmarks several producers and one consumer over MPSC
+func benchmarkMPSCFanIn(b *testing.B) {
+	const size = 1000
+	const numProducers = 4
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](size)))
+	defer free(ptr)
+
+	benchmarkTopology(b, xxchan.MakeMPSC[int](ptr, size), numProducers)
+}
+
+func benchmarkBuiltinChanFanIn(b *testing.B) {
+	const size = 1000
+	const numProducers = 4
+	benchmarkTopology(b, make(builtinChan, size), numProducers)
+}
+
+// benchmarkXXChanStrategies compares lock strategies under contention: four
+// producers waiting on a small channel drained by one consumer, and Push/Pop
+// pairs from every processor at once.
+func benchmarkXXChanStrategies(b *testing.B) {
+	const size = 16
+	const numProducers = 4
+	strategies := []xxchan.LockStrategy{
+		xxchan.LockAdaptive,
+		xxchan.LockSpin,
+		xxchan.LockYield,
+		xxchan.LockBackoff,
+		xxchan.LockFutex,
+		xxchan.LockFutexShared,
+	}
+	for _, strategy := range strategies {
+		b.Run(strategy.String()+"/FanIn", func(b *testing.B) {
+			if strategy == xxchan.LockSpin && runtime.GOMAXPROCS(0) <= numProducers {
+				b.Skip("LockSpin needs a processor per goroutine")
+			}
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
+
+			benchmarkTopology(b, xxchan.Make[int](ptr, size, xxchan.WithLockStrategy(strategy)), numProducers)
+		})
+		b.Run(strategy.String()+"/Parallel", func(b *testing.B) {
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.Make[int](ptr, size, xxchan.WithLockStrategy(strategy))
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if !ch.Push(i) {
+						ch.Pop()
+					}
+				}
+			})
+		})
+	}
+}
+
+// benchmarkXXChanPadding compares the compact and padded layouts with
+// producers and consumers on separate processors: RunParallel goroutines take
+// turns to become producers or consumers, and each makes one non-blocking
+// attempt per iteration.
+func benchmarkXXChanPadding(b *testing.B) {
+	const size = 1024
+	layouts := []struct {
+		name string
+		opts []xxchan.Option
+	}{
+		{"Compact", nil},
+		{"Padded", []xxchan.Option{xxchan.WithPadding()}},
+	}
+	for _, layout := range layouts {
+		b.Run(layout.name+"/Split", func(b *testing.B) {
+			ptr := alloc(uint(xxchan.Sizeof[int](size, layout.opts...)))
+			defer free(ptr)
+
+			ch := xxchan.Make[int](ptr, size, layout.opts...)
+			var roles atomic.Int64
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				producer := roles.Add(1)%2 == 1
+				for i := 0; pb.Next(); i++ {
+					if producer {
+						ch.Push(i)
+					} else {
+						ch.Pop()
+					}
+				}
+			})
+		})
+		b.Run(layout.name+"/Mixed", func(b *testing.B) {
+			ptr := alloc(uint(xxchan.Sizeof[int](size, layout.opts...)))
+			defer free(ptr)
+
+			ch := xxchan.Make[int](ptr, size, layout.opts...)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if !ch.Push(i) {
+						ch.Pop()
+					}
+				}
+			})
+		})
+	}
+}
+
+// benchmarkXXChanPow2 compares capacities just below a power of two, whose
+// slots are found with a division, against the power of two itself, whose
+// slots are found with a mask.
+func benchmarkXXChanPow2(b *testing.B) {
+	for _, size := range []int{1000, 1024, 4000, 4096} {
+		b.Run(fmt.Sprintf("cap-%d", size), func(b *testing.B) {
+			ptr := alloc(uint(xxchan.Sizeof[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.Make[int](ptr, size)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; b.Loop(); i++ {
+				ch.Push(i)
+				ch.Pop()
+			}
+		})
+		b.Run(fmt.Sprintf("SPSC/cap-%d", size), func(b *testing.B) {
+			ptr := alloc(uint(xxchan.SizeofSPSC[int](size)))
+			defer free(ptr)
+
+			ch := xxchan.MakeSPSC[int](ptr, size)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; b.Loop(); i++ {
+				ch.Push(i)
+				ch.Pop()
+			}
+		})
+	}
+}
+
 // Benchmark helper to run all benchmarks
 func BenchmarkAll(b *testing.B) {
 	benchmarks := []struct {
@@ -279,6 +618,19 @@ func BenchmarkAll(b *testing.B) {
 		{"Builtin/Creation", benchmarkBuiltinChanCreation},
 		{"XXChan/Concurrent", benchmarkXXChanConcurrent},
 		{"Builtin/Concurrent", benchmarkBuiltinChanConcurrent},
+		{"XXChan/Batch", benchmarkXXChanBatch},
+		{"SPSC/Mixed", benchmarkSPSCMixed},
+		{"MPSC/Mixed", benchmarkMPSCMixed},
+		{"XXChan/Pipeline", benchmarkXXChanPipeline},
+		{"SPSC/Pipeline", benchmarkSPSCPipeline},
+		{"SPSC/Split", benchmarkSPSCSplit},
+		{"Builtin/Pipeline", benchmarkBuiltinChanPipeline},
+		{"XXChan/FanIn", benchmarkXXChanFanIn},
+		{"MPSC/FanIn", benchmarkMPSCFanIn},
+		{"Builtin/FanIn", benchmarkBuiltinChanFanIn},
+		{"XXChan/Strategy", benchmarkXXChanStrategies},
+		{"XXChan/Padding", benchmarkXXChanPadding},
+		{"XXChan/Pow2", benchmarkXXChanPow2},
 	}
 
 	for _, bm := range benchmarks {
@@ -0,0 +1,437 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Broadcast is a garbage collection-free fan-out channel: every value pushed
+// is seen by every subscribed reader, in the style of the LMAX disruptor.
+//
+// The channel keeps a table of reader cursors in the same user-provided memory
+// block as the ring. Each reader consumes by advancing its own cursor, and
+// producers may only overwrite a slot once every subscribed reader has moved
+// past it, so the slowest reader holds producers back. Values pushed while no
+// reader is subscribed are not retained for later subscribers.
+//
+// Push, Close and Subscribe may be called from any goroutine. Each Reader must
+// be used by one goroutine at a time.
+//
+// Example usage:
+//
+//	size := xxchan.SizeofBroadcast[int](100, 4)
+//	buf := make([]byte, size)
+//	ch := xxchan.MakeBroadcast[int](unsafe.Pointer(&buf[0]), 100, 4)
+//	r, ok := ch.Subscribe()
+//	ch.Push(42)
+//	val, ok := r.Pop()
+type Broadcast[T any] struct {
+	header
+	notifier
+	tail    int64 // Publish position, with closedBit set once closed
+	cap     int64
+	readers int64 // Number of entries in the cursor table
+	_       [0]T  // Zero-sized placeholder for type information; cursors, slot sequences and buffer follow the struct
+}
+
+// cursor is an entry of a Broadcast's reader table.
+type cursor struct {
+	pos    int64 // Next position the reader consumes
+	active int64 // cursorActive while a reader holds the entry; see Subscribe
+}
+
+// States of a cursor's active word. A free entry is 0.
+const (
+	cursorActive  = 1 // Held by a reader whose pos is valid
+	cursorClaimed = 2 // Being set up by Subscribe; pos is not valid yet
+)
+
+// SizeofBroadcast calculates the total memory size required for a
+// Broadcast[T] with the specified capacity and number of reader cursors.
+//
+// Parameters:
+//   - n: The desired capacity of the channel (maximum number of elements)
+//   - readers: The maximum number of readers subscribed at the same time
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the channel
+func SizeofBroadcast[T any](n, readers int) int {
+	if n < 0 || readers < 0 {
+		panic(ErrInvalidCapacity)
+	}
+	size, ok := sizeofChannel(unsafe.Sizeof(Broadcast[T]{}), unsafe.Sizeof(int64(0)), unsafe.Sizeof(*new(T)), n)
+	if !ok || readers > (math.MaxInt-maxAlign-size)/int(unsafe.Sizeof(cursor{})) {
+		panic(ErrSizeOverflow)
+	}
+	return alignUp(size+readers*int(unsafe.Sizeof(cursor{})), int(unsafe.Alignof(Broadcast[T]{})))
+}
+
+// MakeBroadcast initializes a new Broadcast[T] using a pre-allocated memory
+// block of at least SizeofBroadcast[T](n, readers) bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The capacity of the channel (maximum number of elements)
+//   - readers: The maximum number of readers subscribed at the same time
+//   - opts: Options such as WithLockStrategy
+//
+// Returns:
+//   - A pointer to the initialized Broadcast[T]
+//
+// The safety requirements of Make apply unchanged.
+func MakeBroadcast[T any](ptr unsafe.Pointer, n, readers int, opts ...Option) *Broadcast[T] {
+	o := newOptions(opts)
+	c := (*Broadcast[T])(ptr)
+	c.cap = int64(n)
+	c.readers = int64(readers)
+	c.tail = 0
+	c.notifier.init(o.strategy)
+	clear(c.cursors())
+	seqs := c.seqs()
+	for i := range seqs {
+		// Each slot starts out as if its value from the previous lap had been
+		// published and consumed.
+		seqs[i] = int64(i) - c.cap + 1
+	}
+	c.header.init(layoutBroadcast, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]())
+	return c
+}
+
+// AttachBroadcast is like Attach for a block initialized by MakeBroadcast.
+func AttachBroadcast[T any](ptr unsafe.Pointer) (*Broadcast[T], error) {
+	c := (*Broadcast[T])(ptr)
+	if err := c.header.check(layoutBroadcast, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]()); err != nil {
+		return nil, err
+	}
+	if c.cap < 0 || c.readers < 0 {
+		return nil, fmt.Errorf("%w: invalid capacity %d", ErrNotChannel, c.cap)
+	}
+	return c, nil
+}
+
+// cursors returns the reader table, located immediately after the Broadcast
+// struct in memory.
+func (c *Broadcast[T]) cursors() []cursor {
+	addr := unsafe.Add(unsafe.Pointer(c), unsafe.Sizeof(*c))
+	return unsafe.Slice((*cursor)(addr), c.readers)
+}
+
+// seqs returns the per-slot sequence numbers, located after the reader table.
+//
+// A slot holds pos+1 once the value for position pos has been published.
+func (c *Broadcast[T]) seqs() []int64 {
+	addr := unsafe.Add(unsafe.Pointer(c), int(unsafe.Sizeof(*c))+int(c.readers)*int(unsafe.Sizeof(cursor{})))
+	return unsafe.Slice((*int64)(addr), c.cap)
+}
+
+// buffer returns the ring buffer, located after the slot sequences and
+// properly aligned for type T.
+func (c *Broadcast[T]) buffer() []T {
+	offset := int(unsafe.Sizeof(*c)) + int(c.readers)*int(unsafe.Sizeof(cursor{})) + int(c.cap)*int(unsafe.Sizeof(int64(0)))
+	addr := unsafe.Add(unsafe.Pointer(c), alignUp(offset, int(unsafe.Alignof(*new(T)))))
+	return unsafe.Slice((*T)(addr), c.cap)
+}
+
+// slowest returns the position of the slowest subscribed reader, or tail if
+// there is none.
+func (c *Broadcast[T]) slowest(tail int64) int64 {
+	slow := tail
+	cursors := c.cursors()
+	for i := range cursors {
+		if atomic.LoadInt64(&cursors[i].active) == cursorActive {
+			slow = min(slow, atomic.LoadInt64(&cursors[i].pos))
+		}
+	}
+	return slow
+}
+
+// Push attempts to publish a value to every subscribed reader.
+//
+// Returns:
+//   - true if the value was successfully published
+//   - false if the slowest reader is a full ring behind, or the channel is
+//     closed
+func (c *Broadcast[T]) Push(val T) (ok bool) {
+	if c == nil {
+		return
+	}
+	return c.push(val) == nil
+}
+
+// TryPush is like Push but reports why the value could not be published.
+//
+// Returns:
+//   - nil if the value was successfully published
+//   - ErrFull if the slowest reader is a full ring behind
+//   - ErrClosed if the channel has been closed
+func (c *Broadcast[T]) TryPush(val T) error {
+	if c == nil {
+		return ErrFull
+	}
+	return c.push(val)
+}
+
+// push implements TryPush.
+func (c *Broadcast[T]) push(val T) error {
+	seqs := c.seqs()
+	for attempt := 0; ; attempt++ {
+		tail := atomic.LoadInt64(&c.tail)
+		if tail&closedBit != 0 {
+			return ErrClosed
+		}
+		if c.cap == 0 {
+			return ErrFull // A channel without slots is always full
+		}
+		i := tail % c.cap
+		if atomic.LoadInt64(&seqs[i]) != tail-c.cap+1 || tail-c.slowest(tail) >= c.cap {
+			// The previous lap's value is still being written or read.
+			if atomic.LoadInt64(&c.tail) != tail {
+				continue
+			}
+			return ErrFull
+		}
+		if atomic.CompareAndSwapInt64(&c.tail, tail, tail+1) {
+			c.buffer()[i] = val
+			atomic.StoreInt64(&seqs[i], tail+1)
+			c.notify()
+			return nil
+		}
+		c.backoff(attempt)
+	}
+}
+
+// PushWait publishes a value, waiting for the slowest reader to make room.
+//
+// Parameters:
+//   - ctx: Context that bounds the wait
+//   - val: The value to publish
+//
+// Returns:
+//   - nil if the value was published
+//   - ErrClosed if the channel is or becomes closed while waiting
+//   - ctx.Err() if ctx was done before space became available
+func (c *Broadcast[T]) PushWait(ctx context.Context, val T) error {
+	if c == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := c.push(val); err != ErrFull {
+		return err
+	}
+	return pushWait(ctx, c, val)
+}
+
+// Close marks the channel as closed.
+//
+// After Close, Push fails. Every reader can still pop the values published
+// before Close; once a reader has drained them, its TryPop and PopWait return
+// ErrClosed.
+//
+// Returns:
+//   - nil if the channel was closed by this call
+//   - ErrClosed if the channel had already been closed
+func (c *Broadcast[T]) Close() error {
+	if c == nil {
+		return ErrClosed
+	}
+	for {
+		tail := atomic.LoadInt64(&c.tail)
+		if tail&closedBit != 0 {
+			return ErrClosed
+		}
+		if atomic.CompareAndSwapInt64(&c.tail, tail, tail|closedBit) {
+			c.wake()
+			return nil
+		}
+	}
+}
+
+// Closed reports whether Close has been called on the channel.
+func (c *Broadcast[T]) Closed() bool {
+	if c == nil {
+		return false
+	}
+	return atomic.LoadInt64(&c.tail)&closedBit != 0
+}
+
+// Len returns the number of values the slowest subscribed reader has yet to
+// pop, or 0 if no reader is subscribed.
+func (c *Broadcast[T]) Len() int {
+	if c == nil {
+		return 0
+	}
+	tail := atomic.LoadInt64(&c.tail) &^ closedBit
+	return int(tail - c.slowest(tail))
+}
+
+// Cap returns the maximum number of values a reader can fall behind.
+func (c *Broadcast[T]) Cap() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.cap)
+}
+
+// Readers returns the number of currently subscribed readers.
+func (c *Broadcast[T]) Readers() int {
+	if c == nil {
+		return 0
+	}
+	n := 0
+	cursors := c.cursors()
+	for i := range cursors {
+		if atomic.LoadInt64(&cursors[i].active) == cursorActive {
+			n++
+		}
+	}
+	return n
+}
+
+// Subscribe registers a new reader that sees every value published from now
+// on.
+//
+// Returns:
+//   - The reader, which must be released with Close when no longer used
+//   - false if every entry of the reader table is taken
+func (c *Broadcast[T]) Subscribe() (r Reader[T], ok bool) {
+	if c == nil {
+		return r, false
+	}
+	cursors := c.cursors()
+	for i := range cursors {
+		cur := &cursors[i]
+		if atomic.LoadInt64(&cur.active) != 0 {
+			continue
+		}
+		// Claim the entry before touching pos, so that a concurrent Subscribe
+		// cannot overwrite the cursor of the one that won it; producers skip
+		// the entry until it is published as active.
+		if !atomic.CompareAndSwapInt64(&cur.active, 0, cursorClaimed) {
+			continue
+		}
+		atomic.StoreInt64(&cur.pos, atomic.LoadInt64(&c.tail)&^closedBit)
+		atomic.StoreInt64(&cur.active, cursorActive)
+		// A producer that scanned the table before the entry became active
+		// may still claim one more position without seeing it; starting at
+		// the current tail keeps this reader clear of any such overwrite.
+		atomic.StoreInt64(&cur.pos, atomic.LoadInt64(&c.tail)&^closedBit)
+		return Reader[T]{c: c, cur: cur}, true
+	}
+	return r, false
+}
+
+// Reader is a subscription to a Broadcast[T], returned by Subscribe. It pops
+// values in publish order, independently of other readers.
+type Reader[T any] struct {
+	c   *Broadcast[T]
+	cur *cursor
+}
+
+// Pop removes the next value from this reader's view of the channel without
+// blocking.
+//
+// Returns:
+//   - v: The next value, or zero value of T if none is available
+//   - ok: true if a value was popped
+func (r Reader[T]) Pop() (v T, ok bool) {
+	v, err := r.pop()
+	return v, err == nil
+}
+
+// TryPop is like Pop but reports why no value could be popped.
+//
+// Returns:
+//   - v: The next value, or zero value of T on error
+//   - err: nil on success, ErrEmpty if no value is available yet, or
+//     ErrClosed if the channel is closed and this reader has drained it
+func (r Reader[T]) TryPop() (v T, err error) {
+	return r.pop()
+}
+
+// pop implements TryPop.
+func (r Reader[T]) pop() (v T, err error) {
+	if r.c == nil {
+		return v, ErrEmpty
+	}
+	c := r.c
+	pos := atomic.LoadInt64(&r.cur.pos)
+	// A channel without slots never publishes, so it is always empty.
+	if c.cap == 0 || atomic.LoadInt64(&c.seqs()[pos%c.cap]) != pos+1 {
+		if tail := atomic.LoadInt64(&c.tail); tail&closedBit != 0 && tail&^closedBit == pos {
+			return v, ErrClosed
+		}
+		return v, ErrEmpty
+	}
+	i := pos % c.cap
+	v = c.buffer()[i]
+	atomic.StoreInt64(&r.cur.pos, pos+1)
+	c.notify()
+	return v, nil
+}
+
+// PopWait removes the next value, waiting until one is published.
+//
+// Parameters:
+//   - ctx: Context that bounds the wait
+//
+// Returns:
+//   - v: The next value, or zero value of T on error
+//   - err: nil on success, ErrClosed if the channel is closed and this reader
+//     has drained it, or ctx.Err() if ctx was done before a value arrived
+func (r Reader[T]) PopWait(ctx context.Context) (v T, err error) {
+	if r.c == nil {
+		<-ctx.Done()
+		return v, ctx.Err()
+	}
+	if v, err = r.pop(); err != ErrEmpty {
+		return
+	}
+	return popWait(ctx, r)
+}
+
+// Lag returns the number of values published that this reader has not
+// popped yet.
+func (r Reader[T]) Lag() int {
+	if r.c == nil {
+		return 0
+	}
+	tail := atomic.LoadInt64(&r.c.tail) &^ closedBit
+	return int(tail - atomic.LoadInt64(&r.cur.pos))
+}
+
+// Close unsubscribes the reader, releasing its entry in the reader table and
+// no longer holding producers back. The reader must not be used afterwards.
+func (r Reader[T]) Close() {
+	if r.c == nil {
+		return
+	}
+	atomic.StoreInt64(&r.cur.active, 0)
+	r.c.wake()
+}
+
+// watch, unwatch and wait let PopWait share the channel's notifier.
+func (r Reader[T]) watch() int32 { return r.c.watch() }
+
+func (r Reader[T]) unwatch() { r.c.unwatch() }
+
+func (r Reader[T]) wait(ctx context.Context, seq int32) error { return r.c.wait(ctx, seq) }
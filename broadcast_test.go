@@ -0,0 +1,236 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestBroadcast(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 2
+	ptr := alloc(uint(xxchan.SizeofBroadcast[int](n, 2)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBroadcast[int](ptr, n, 2)
+	assert.Equal(n, ch.Cap())
+
+	// Without readers, values are not retained.
+	assert.True(ch.Push(-1))
+	assert.Equal(0, ch.Len())
+
+	r1, ok := ch.Subscribe()
+	assert.True(ok)
+	r2, ok := ch.Subscribe()
+	assert.True(ok)
+	_, ok = ch.Subscribe()
+	assert.False(ok)
+	assert.Equal(2, ch.Readers())
+
+	_, ok = r1.Pop()
+	assert.False(ok)
+
+	assert.True(ch.Push(1))
+	assert.True(ch.Push(2))
+	assert.ErrorIs(ch.TryPush(3), xxchan.ErrFull)
+	assert.Equal(2, r1.Lag())
+
+	// Both readers see every value; the slower one holds producers back.
+	val, ok := r1.Pop()
+	assert.True(ok)
+	assert.Equal(1, val)
+	val, ok = r1.Pop()
+	assert.True(ok)
+	assert.Equal(2, val)
+	assert.Equal(0, r1.Lag())
+	assert.Equal(2, r2.Lag())
+	assert.False(ch.Push(3))
+
+	val, ok = r2.Pop()
+	assert.True(ok)
+	assert.Equal(1, val)
+	assert.True(ch.Push(3))
+
+	// An unsubscribed reader no longer holds producers back.
+	r2.Close()
+	assert.Equal(1, ch.Readers())
+	assert.True(ch.Push(4))
+	assert.Equal(2, r1.Lag())
+
+	assert.NoError(ch.Close())
+	assert.False(ch.Push(5))
+	val, err := r1.TryPop()
+	assert.NoError(err)
+	assert.Equal(3, val)
+	val, err = r1.TryPop()
+	assert.NoError(err)
+	assert.Equal(4, val)
+	_, err = r1.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+
+	again, err := xxchan.AttachBroadcast[int](ptr)
+	assert.NoError(err)
+	assert.Equal(1, again.Readers())
+}
+
+func TestBroadcastZeroCapacity(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofBroadcast[int](0, 1)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBroadcast[int](ptr, 0, 1)
+	attached, err := xxchan.AttachBroadcast[int](ptr)
+	assert.NoError(err)
+	assert.Equal(0, attached.Cap())
+
+	r, ok := ch.Subscribe()
+	assert.True(ok)
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrFull)
+	_, err = r.TryPop()
+	assert.ErrorIs(err, xxchan.ErrEmpty)
+
+	assert.NoError(ch.Close())
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrClosed)
+	_, err = r.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestBroadcastConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n, readers, producers, perProducer := 8, 3, 2, 1000
+	ptr := alloc(uint(xxchan.SizeofBroadcast[int](n, readers)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBroadcast[int](ptr, n, readers)
+	ctx := context.Background()
+
+	sums := make([]int, readers)
+	var rg sync.WaitGroup
+	for i := range readers {
+		r, ok := ch.Subscribe()
+		assert.True(ok)
+		rg.Add(1)
+		go func() {
+			defer rg.Done()
+			defer r.Close()
+			for {
+				v, err := r.PopWait(ctx)
+				if err != nil {
+					return
+				}
+				sums[i] += v
+			}
+		}()
+	}
+
+	var pg sync.WaitGroup
+	for range producers {
+		pg.Add(1)
+		go func() {
+			defer pg.Done()
+			for i := 1; i <= perProducer; i++ {
+				if err := ch.PushWait(ctx, i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	pg.Wait()
+	assert.NoError(ch.Close())
+	rg.Wait()
+
+	want := producers * perProducer * (perProducer + 1) / 2
+	for _, sum := range sums {
+		assert.Equal(want, sum)
+	}
+	assert.Equal(0, ch.Readers())
+}
+
+func TestBroadcastConcurrentSubscribe(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n, readers, subscribers, values := 8, 4, 32, 1000
+	ptr := alloc(uint(xxchan.SizeofBroadcast[int](n, readers)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBroadcast[int](ptr, n, readers)
+	ctx := context.Background()
+
+	var pg sync.WaitGroup
+	pg.Add(1)
+	go func() {
+		defer pg.Done()
+		for i := 1; i <= values; i++ {
+			if !ch.Push(i) {
+				_ = ch.PushWait(ctx, i)
+			}
+		}
+	}()
+
+	// Each entry goes to exactly one subscriber, whose cursor no losing
+	// subscriber may move: it sees consecutive values from where it started.
+	var subscribed atomic.Int64
+	var attempted, rg sync.WaitGroup
+	for range subscribers {
+		attempted.Add(1)
+		rg.Add(1)
+		go func() {
+			defer rg.Done()
+			r, ok := ch.Subscribe()
+			attempted.Done()
+			if !ok {
+				return
+			}
+			subscribed.Add(1)
+			defer r.Close()
+			prev := 0
+			for {
+				v, err := r.PopWait(ctx)
+				if err != nil {
+					return
+				}
+				if prev != 0 && v != prev+1 {
+					t.Errorf("reader skipped from %d to %d", prev, v)
+				}
+				prev = v
+			}
+		}()
+	}
+	attempted.Wait()
+	assert.Equal(int64(readers), subscribed.Load())
+	assert.Equal(readers, ch.Readers())
+
+	pg.Wait()
+	assert.NoError(ch.Close())
+	rg.Wait()
+	assert.Equal(0, ch.Readers())
+}
@@ -0,0 +1,228 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// recordAlign is the granularity of ByteChannel records. Each record
+	// starts with an 8-byte header, so the header never straddles the end of
+	// the ring.
+	recordAlign = 8
+
+	// recordPad marks a header that skips the rest of the ring because the
+	// next record did not fit before its end.
+	recordPad = int64(-1)
+)
+
+// ByteChannel is a garbage collection-free channel of variable-length byte
+// records that operates on a user-provided memory block.
+//
+// Records are stored back to back in a byte ring, each preceded by an 8-byte
+// header holding its length. A record that would run past the end of the ring
+// is placed at the front instead, with a padding marker in the skipped space.
+// Each record takes its length rounded up to 8 bytes plus the header, so the
+// ring holds many small records or a few large ones.
+//
+// Writers claim space with a single compare-and-swap on the tail, and the
+// header is written last, so a record becomes visible only once its bytes are
+// in place. Write, Len and Cap may be called from any goroutine; Read must be
+// called from at most one goroutine at a time, as with MPSC[T].
+//
+// Example usage:
+//
+//	size := xxchan.SizeofBytes(64 << 10)
+//	buf := make([]byte, size)
+//	ch := xxchan.MakeBytes(unsafe.Pointer(&buf[0]), 64<<10)
+//	ch.Write([]byte("hello"))
+//	n, ok := ch.Read(dst)
+type ByteChannel struct {
+	header
+	head int64 // Read position, owned by the reader
+	tail int64 // Write position
+	cap  int64 // Size of the ring in bytes, a multiple of recordAlign
+}
+
+// SizeofBytes calculates the total memory size required for a ByteChannel
+// whose ring holds n bytes of records, headers included.
+//
+// Parameters:
+//   - n: The desired size of the ring in bytes
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the channel
+func SizeofBytes(n int) int {
+	if n < 0 {
+		panic(ErrInvalidCapacity)
+	}
+	size, ok := sizeofChannel(unsafe.Sizeof(ByteChannel{}), 0, 1, alignUp(n, recordAlign))
+	if !ok {
+		panic(ErrSizeOverflow)
+	}
+	return size
+}
+
+// MakeBytes initializes a new ByteChannel using a pre-allocated memory block
+// of at least SizeofBytes(n) bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The size of the ring in bytes
+//
+// Returns:
+//   - A pointer to the initialized ByteChannel
+//
+// The safety requirements of Make apply unchanged.
+func MakeBytes(ptr unsafe.Pointer, n int) *ByteChannel {
+	c := (*ByteChannel)(ptr)
+	c.cap = int64(alignUp(n, recordAlign))
+	c.head = 0
+	c.tail = 0
+	clear(c.ring())
+	c.header.init(layoutBytes, 1, 1, fingerprintOf[byte]())
+	return c
+}
+
+// AttachBytes is like Attach for a block initialized by MakeBytes.
+func AttachBytes(ptr unsafe.Pointer) (*ByteChannel, error) {
+	c := (*ByteChannel)(ptr)
+	if err := c.header.check(layoutBytes, 1, 1, fingerprintOf[byte]()); err != nil {
+		return nil, err
+	}
+	if c.cap < 0 {
+		return nil, fmt.Errorf("%w: invalid capacity %d", ErrNotChannel, c.cap)
+	}
+	return c, nil
+}
+
+// ring returns the record ring, located immediately after the ByteChannel
+// struct in memory.
+func (c *ByteChannel) ring() []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(c), unsafe.Sizeof(*c))), c.cap)
+}
+
+// record returns the header word of the record at offset off of the ring.
+//
+// The word is 0 while the space is free or still being written, the record
+// length plus one once the record is complete, and recordPad for padding.
+func (c *ByteChannel) record(off int64) *int64 {
+	return (*int64)(unsafe.Add(unsafe.Pointer(c), int64(unsafe.Sizeof(*c))+off))
+}
+
+// Write appends p to the channel as one record without blocking.
+//
+// Returns:
+//   - true if the record was successfully added
+//   - false if the ring has no room for it
+func (c *ByteChannel) Write(p []byte) (ok bool) {
+	if c == nil {
+		return false
+	}
+	size := int64(recordAlign + alignUp(len(p), recordAlign))
+	if size > c.cap {
+		return false // Also covers a ring of size zero, which holds no record
+	}
+	for {
+		head := atomic.LoadInt64(&c.head)
+		tail := atomic.LoadInt64(&c.tail)
+		off := tail % c.cap
+		pad := int64(0)
+		if off+size > c.cap {
+			pad = c.cap - off
+		}
+		if tail+pad+size-head > c.cap {
+			return false
+		}
+		if !atomic.CompareAndSwapInt64(&c.tail, tail, tail+pad+size) {
+			continue
+		}
+		if pad > 0 {
+			atomic.StoreInt64(c.record(off), recordPad)
+			off = 0
+		}
+		copy(c.ring()[off+recordAlign:], p)
+		atomic.StoreInt64(c.record(off), int64(len(p))+1)
+		return true
+	}
+}
+
+// Read removes the oldest record from the channel and copies it into dst
+// without blocking.
+//
+// If dst is too short for the record, Read leaves the record queued and
+// returns its length with false, so that the caller can retry with a larger
+// buffer.
+//
+// Returns:
+//   - The length of the record
+//   - true if the record was copied into dst and removed, false if the
+//     channel is empty or dst is too short
+func (c *ByteChannel) Read(dst []byte) (n int, ok bool) {
+	if c == nil || c.cap == 0 {
+		return 0, false
+	}
+	ring := c.ring()
+	head := atomic.LoadInt64(&c.head)
+	for {
+		off := head % c.cap
+		word := atomic.LoadInt64(c.record(off))
+		switch {
+		case word == 0:
+			return 0, false
+		case word == recordPad:
+			atomic.StoreInt64(c.record(off), 0)
+			head += c.cap - off
+			atomic.StoreInt64(&c.head, head)
+			continue
+		}
+		n = int(word - 1)
+		if len(dst) < n {
+			return n, false
+		}
+		size := int64(recordAlign + alignUp(n, recordAlign))
+		copy(dst, ring[off+recordAlign:off+recordAlign+int64(n)])
+		// Free space must read as zero, so that a writer's header is the
+		// only thing that can make it look like a record.
+		clear(ring[off+recordAlign : off+size])
+		atomic.StoreInt64(c.record(off), 0)
+		atomic.StoreInt64(&c.head, head+size)
+		return n, true
+	}
+}
+
+// Len returns the number of bytes of the ring currently in use, including
+// record headers, alignment and padding.
+func (c *ByteChannel) Len() int {
+	if c == nil {
+		return 0
+	}
+	head := atomic.LoadInt64(&c.head)
+	return int(atomic.LoadInt64(&c.tail) - head)
+}
+
+// Cap returns the size of the ring in bytes.
+func (c *ByteChannel) Cap() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.cap)
+}
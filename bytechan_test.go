@@ -0,0 +1,163 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestByteChannel(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 64
+	ptr := alloc(uint(xxchan.SizeofBytes(n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBytes(ptr, n)
+	assert.Equal(n, ch.Cap())
+
+	buf := make([]byte, 32)
+	_, ok := ch.Read(buf)
+	assert.False(ok)
+
+	// "hello" takes an 8-byte header plus 8 bytes of data.
+	assert.True(ch.Write([]byte("hello")))
+	assert.True(ch.Write(nil))
+	assert.Equal(24, ch.Len())
+
+	_, ok = ch.Read(buf[:2])
+	assert.False(ok)
+	nr, ok := ch.Read(buf)
+	assert.True(ok)
+	assert.Equal("hello", string(buf[:nr]))
+	nr, ok = ch.Read(buf)
+	assert.True(ok)
+	assert.Equal(0, nr)
+	assert.Equal(0, ch.Len())
+
+	// A record larger than the ring never fits.
+	assert.False(ch.Write(make([]byte, n)))
+
+	// The ring is empty at offset 24. A 24-byte record fills it up to 48;
+	// the next one does not fit before the end and wraps to the front,
+	// padding the last 16 bytes.
+	assert.True(ch.Write(bytes.Repeat([]byte{'a'}, 16)))
+	assert.True(ch.Write(bytes.Repeat([]byte{'b'}, 16)))
+	assert.Equal(64, ch.Len())
+	assert.False(ch.Write([]byte{'c'}))
+
+	nr, ok = ch.Read(buf[:8])
+	assert.False(ok)
+	assert.Equal(16, nr)
+	nr, ok = ch.Read(buf)
+	assert.True(ok)
+	assert.Equal(bytes.Repeat([]byte{'a'}, 16), buf[:nr])
+	nr, ok = ch.Read(buf)
+	assert.True(ok)
+	assert.Equal(bytes.Repeat([]byte{'b'}, 16), buf[:nr])
+	assert.Equal(0, ch.Len())
+
+	// A ring of size zero holds no record, not even an empty one.
+	ch = xxchan.MakeBytes(ptr, 0)
+	assert.False(ch.Write(nil))
+	_, ok = ch.Read(buf)
+	assert.False(ok)
+	attached, err := xxchan.AttachBytes(ptr)
+	assert.NoError(err)
+	assert.Equal(0, attached.Cap())
+}
+
+func TestByteChannelAttach(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 64
+	ptr := alloc(uint(xxchan.SizeofBytes(n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBytes(ptr, n)
+	assert.True(ch.Write([]byte("kept")))
+
+	again, err := xxchan.AttachBytes(ptr)
+	assert.NoError(err)
+	buf := make([]byte, 8)
+	nr, ok := again.Read(buf)
+	assert.True(ok)
+	assert.Equal("kept", string(buf[:nr]))
+
+	_, err = xxchan.Attach[byte](ptr)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+}
+
+func TestByteChannelConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 1024
+	ptr := alloc(uint(xxchan.SizeofBytes(n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeBytes(ptr, n)
+
+	producers, perProducer := 4, 1000
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := make([]byte, 0, 256)
+			for i := range perProducer {
+				// Each record carries its producer and sequence number,
+				// followed by a filler of varying length.
+				rec = binary.LittleEndian.AppendUint32(rec[:0], uint32(p))
+				rec = binary.LittleEndian.AppendUint32(rec, uint32(i))
+				rec = append(rec, bytes.Repeat([]byte{byte(i)}, i%200)...)
+				for !ch.Write(rec) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	next := make([]uint32, producers)
+	buf := make([]byte, 256)
+	for got := 0; got < producers*perProducer; {
+		nr, ok := ch.Read(buf)
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		got++
+		p := binary.LittleEndian.Uint32(buf)
+		i := binary.LittleEndian.Uint32(buf[4:])
+		assert.Equal(next[p], i)
+		assert.Equal(8+int(i)%200, nr)
+		assert.Equal(bytes.Repeat([]byte{byte(i)}, int(i)%200), buf[8:nr])
+		next[p]++
+	}
+	wg.Wait()
+	assert.Equal(0, ch.Len())
+}
@@ -20,11 +20,30 @@
 package xxchan
 
 import (
+	"context"
+	"errors"
 	"sync/atomic"
-	"time"
 	"unsafe"
 )
 
+var (
+	// ErrClosed is returned when pushing to a closed channel, and when popping
+	// from a closed channel that has been drained.
+	ErrClosed = errors.New("xxchan: channel closed")
+
+	// ErrFull is returned by TryPush when the channel has no free space.
+	ErrFull = errors.New("xxchan: channel full")
+
+	// ErrEmpty is returned by TryPop when the channel holds no values but is
+	// still open.
+	ErrEmpty = errors.New("xxchan: channel empty")
+)
+
+// closedBit marks the enqueue position of a closed channel. Folding the flag
+// into tail makes Close and Push linearizable: a Push whose compare-and-swap
+// on tail succeeds happened before Close, and every later one fails.
+const closedBit = int64(1) << 62
+
 // Channel is a lock-free, garbage collection-free channel implementation that operates
 // on a user-provided memory block. It supports concurrent access from multiple goroutines
 // and provides fundamental channel operations including Push, Pop, Len, and Cap.
@@ -33,8 +52,10 @@ import (
 // making it suitable for scenarios where garbage collector managed memory
 // cannot be used, such as in certain system-level or embedded applications.
 //
-// The channel uses a ring buffer internally and employs atomic operations
-// with spin-lock synchronization to ensure thread safety.
+// The channel is a bounded multi-producer multi-consumer ring buffer in the
+// style of Dmitry Vyukov's queue: every slot carries a sequence number, and
+// producers and consumers claim slots with a single compare-and-swap on the
+// tail or head position, so no operation ever waits for a lock holder.
 //
 // Example usage:
 //
@@ -51,11 +72,15 @@ import (
 //	ch.Push(42)
 //	val, ok := ch.Pop()
 type Channel[T any] struct {
-	l    int32
-	head int64
-	tail int64
-	cap  int64
-	_    [0]T // Zero-sized placeholder for type information; actual buffer follows the struct
+	header
+	cap      int64
+	mask     int64    // cap-1 if cap is a power of two, otherwise -1; see index
+	overflow int64    // Overflow policy applied by Push
+	stride   int64    // Distance between the hot words that follow the struct; see WithPadding
+	words    int64    // Bookkeeping words per slot: the sequence number, its owner with WithRecovery and its checksum if persistent
+	sums     int64    // 1 if every slot carries a checksum of its value; see CreatePersistent
+	stats    counters // Operation counters reported by Stats in the compact layout; see counters
+	_        [0]T     // Zero-sized placeholder for type information; hot words, slot sequences and buffer follow the struct
 }
 
 // alignUp rounds up n to the nearest multiple of align.
@@ -64,34 +89,90 @@ func alignUp(n, align int) int {
 	return (n + align - 1) &^ (align - 1)
 }
 
+// ringMask returns the mask that maps a position to its slot in a ring of
+// capacity n, or -1 if n is not a power of two and a division is needed.
+func ringMask(n int64) int64 {
+	if n > 0 && n&(n-1) == 0 {
+		return n - 1
+	}
+	return -1
+}
+
+// copyToRing copies src into the ring buffer starting at slot start, wrapping
+// around to the front of buf when it runs past the end.
+func copyToRing[T any](buf []T, start int, src []T) {
+	n := copy(buf[start:], src)
+	copy(buf, src[n:])
+}
+
+// copyFromRing fills dst from the ring buffer starting at slot start, wrapping
+// around to the front of buf when it runs past the end.
+func copyFromRing[T any](dst []T, buf []T, start int) {
+	n := copy(dst, buf[start:])
+	copy(dst[n:], buf)
+}
+
 // Sizeof calculates the total memory size required for a Channel[T] with the specified capacity.
 //
 // The function accounts for:
 //   - The size of the Channel[T] struct itself
+//   - The head and tail positions and the wait word, spaced out on separate
+//     cache lines if WithPadding is given
+//   - One sequence number per slot
 //   - Storage space for n elements of type T
 //   - Proper memory alignment requirements for type T
 //
 // Parameters:
 //   - n: The desired capacity of the channel (maximum number of elements)
+//   - opts: The layout options that will be passed to Make; only WithPadding
+//     affects the size
 //
 // Returns:
 //   - The total size in bytes that should be allocated for the channel
 //
 // The returned size should be used when allocating memory before calling Make.
-func Sizeof[T any](n int) int {
-	size := int(unsafe.Sizeof(Channel[T]{})) + n*int(unsafe.Sizeof(*new(T)))
-	return alignUp(size, int(unsafe.Alignof(new(T))))
+// Sizeof panics if n is negative or the size does not fit in an int; New
+// reports the same conditions as errors.
+func Sizeof[T any](n int, opts ...Option) int {
+	if n < 0 {
+		panic(ErrInvalidCapacity)
+	}
+	o := newOptions(opts)
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		panic(ErrSizeOverflow)
+	}
+	return size
+}
+
+// sizeofMPMC returns the size of a Channel[T] block of capacity n whose hot
+// words are stride bytes apart and whose slots carry the given number of
+// bookkeeping words, reporting false if it does not fit in an int.
+func sizeofMPMC[T any](n int, stride, words int64) (int, bool) {
+	size, ok := sizeofChannel(hotOffset[T](stride)+3*uintptr(stride), uintptr(words)*unsafe.Sizeof(int64(0)), unsafe.Sizeof(*new(T)), n)
+	if !ok {
+		return 0, false
+	}
+	return alignUp(size, int(unsafe.Alignof(Channel[T]{}))), true
 }
 
 // Make initializes a new Channel[T] using a pre-allocated memory block.
 //
-// The provided memory block must be at least Sizeof[T](n) bytes in size
-// and properly aligned. The function does not perform memory allocation;
+// The provided memory block must be at least Sizeof[T](n, opts...) bytes in size
+// and aligned to 8 bytes. The function does not perform memory allocation;
 // it only initializes the channel structure within the given memory.
+// Whatever the block held before is overwritten; use Attach to reuse a block
+// that already holds a channel.
+//
+// A power-of-two capacity is detected and lets Push and Pop find a slot with
+// a mask rather than a 64-bit division. A capacity of zero is allowed and
+// gives a channel that is always both full and empty.
 //
 // Parameters:
 //   - ptr: Pointer to the pre-allocated memory block
 //   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithOverflow, WithLockStrategy or WithPadding;
+//     Make performs no checks, so AllowPointers has no effect
 //
 // Returns:
 //   - A pointer to the initialized Channel[T]
@@ -99,109 +180,536 @@ func Sizeof[T any](n int) int {
 // Safety:
 //   - The caller must ensure the memory block remains valid for the channel's lifetime
 //   - The memory block must be at least Sizeof[T](n) bytes
-//   - The pointer must be properly aligned for the target type T
+//   - The pointer must be aligned to 8 bytes, even on 32-bit platforms
 //
 // Example:
 //
 //	size := Sizeof[int](10)
 //	buf := make([]byte, size)
 //	ch := Make[int](unsafe.Pointer(&buf[0]), 10)
-func Make[T any](ptr unsafe.Pointer, n int) *Channel[T] {
+func Make[T any](ptr unsafe.Pointer, n int, opts ...Option) *Channel[T] {
+	o := newOptions(opts)
 	c := (*Channel[T])(ptr)
 	c.cap = int64(n)
-	c.head = 0
-	c.tail = 0
-	c.l = 0
+	c.mask = ringMask(c.cap)
+	c.overflow = int64(o.overflow)
+	c.stride = o.stride()
+	c.words = o.slotWords()
+	c.sums = 0
+	if o.checksums {
+		c.sums = 1
+	}
+	*c.counters() = counters{}
+	*c.headp() = 0
+	*c.tailp() = 0
+	c.events().init(o.strategy)
+	seqs := c.seqs()
+	for i := range seqs {
+		seqs[i] = 2 * int64(i)
+	}
+	clear(c.owners())
+	clear(c.checksums())
+	c.initHeader()
 	return c
 }
 
-// acquireLock acquires an exclusive acquireLock on the channel using atomic compare-and-swap.
-// Uses spin-waiting with microsecond delays to reduce CPU usage while waiting.
-func (c *Channel[T]) acquireLock() {
-	for !atomic.CompareAndSwapInt32(&c.l, 0, 1) {
-		time.Sleep(time.Microsecond) // Spin-wait with brief pause
+// index returns the slot holding position pos. Positions are free-running
+// 64-bit counters, so with a power-of-two capacity the slot is found with a
+// mask instead of a 64-bit division.
+func (c *Channel[T]) index(pos int64) int64 {
+	if c.mask >= 0 {
+		return pos & c.mask
+	}
+	return pos % c.cap
+}
+
+// hotOffset returns the offset of the head position from the start of a
+// Channel[T] whose hot words are stride bytes apart. The compact layout packs
+// them right after the struct. The padded one first leaves a line between the
+// struct and head for the counters, which Push and Pop update on their slow
+// paths, so that they share a line with neither the read-mostly fields of the
+// struct nor head.
+func hotOffset[T any](stride int64) uintptr {
+	if stride == compactStride {
+		return unsafe.Sizeof(Channel[T]{})
+	}
+	return unsafe.Sizeof(Channel[T]{}) + 2*uintptr(stride)
+}
+
+// counters returns the operation counters: the stats field in the compact
+// layout, or the line before head in the padded one.
+func (c *Channel[T]) counters() *counters {
+	if c.stride == compactStride {
+		return &c.stats
+	}
+	return (*counters)(unsafe.Add(unsafe.Pointer(c), unsafe.Sizeof(*c)+uintptr(c.stride)))
+}
+
+// hot returns the address of the i-th hot word: head, tail, the notifier and,
+// past them, the slot sequence numbers.
+func (c *Channel[T]) hot(i int64) unsafe.Pointer {
+	return unsafe.Add(unsafe.Pointer(c), hotOffset[T](c.stride)+uintptr(i*c.stride))
+}
+
+// headp returns the dequeue position.
+func (c *Channel[T]) headp() *int64 {
+	return (*int64)(c.hot(0))
+}
+
+// tailp returns the enqueue position, with closedBit set once closed.
+func (c *Channel[T]) tailp() *int64 {
+	return (*int64)(c.hot(1))
+}
+
+// events returns the notifier used by PushWait, PopWait and Select.
+func (c *Channel[T]) events() *notifier {
+	return (*notifier)(c.hot(2))
+}
+
+// watch, unwatch and wait make Channel[T] a waitable.
+func (c *Channel[T]) watch() int32 {
+	return c.events().watch()
+}
+
+func (c *Channel[T]) unwatch() {
+	c.events().unwatch()
+}
+
+func (c *Channel[T]) wait(ctx context.Context, seq int32) error {
+	return c.events().wait(ctx, seq)
+}
+
+// seqs returns the per-slot sequence numbers, located after the hot words.
+//
+// A slot holds 2*pos while it is free for the enqueue at position pos, 2*pos+1
+// once that value has been published, and 2*(pos+cap) once it has been
+// consumed and is free for the next lap. Doubling keeps the published and free
+// states apart even when cap is 1.
+func (c *Channel[T]) seqs() []int64 {
+	return unsafe.Slice((*int64)(c.hot(3)), c.cap)
+}
+
+// owners returns the per-slot owner stamps, located after the sequence
+// numbers, or nil unless the channel was made WithRecovery.
+func (c *Channel[T]) owners() []int64 {
+	if c.words-c.sums < 2 {
+		return nil
 	}
+	return unsafe.Slice((*int64)(unsafe.Add(c.hot(3), c.cap*int64(unsafe.Sizeof(int64(0))))), c.cap)
 }
 
-// releaseLock releases the exclusive lock on the channel.
-func (c *Channel[T]) releaseLock() {
-	atomic.StoreInt32(&c.l, 0)
+// checksums returns the per-slot checksums, located after the sequence
+// numbers and owner stamps, or nil unless the channel is persistent.
+func (c *Channel[T]) checksums() []int64 {
+	if c.sums == 0 {
+		return nil
+	}
+	return unsafe.Slice((*int64)(unsafe.Add(c.hot(3), (c.words-1)*c.cap*int64(unsafe.Sizeof(int64(0))))), c.cap)
 }
 
 // buffer returns a slice view of the internal ring buffer.
-// The buffer is located immediately after the Channel struct in memory,
-// properly aligned for type T.
+// The buffer is located after the slot sequence numbers, owner stamps and
+// checksums in memory, properly aligned for type T.
 func (c *Channel[T]) buffer() []T {
 	if c == nil {
 		return nil
 	}
-	structSize := unsafe.Sizeof(Channel[T]{})
-	alignedOffset := alignUp(int(structSize), int(unsafe.Alignof(*new(T))))
+	structSize := int(hotOffset[T](c.stride)) + 3*int(c.stride) + int(c.cap*c.words)*int(unsafe.Sizeof(int64(0)))
+	alignedOffset := alignUp(structSize, int(unsafe.Alignof(*new(T))))
 	addr := unsafe.Pointer(uintptr(unsafe.Pointer(c)) + uintptr(alignedOffset))
 	return unsafe.Slice((*T)(addr), c.cap)
 }
 
 // Push attempts to add a value to the channel.
 //
-// The operation is atomic and thread-safe. If the channel is at full capacity,
-// the operation fails immediately without blocking.
+// The operation is atomic and thread-safe. If the channel is at full capacity
+// or has been closed, the operation fails immediately without blocking, unless
+// the channel was created with an overflow policy other than OverflowReject;
+// see MakeWithOverflow and PushEvict.
 //
 // Parameters:
 //   - val: The value to add to the channel
 //
 // Returns:
 //   - true if the value was successfully added
-//   - false if the channel is full
+//   - false if the channel is full or closed, or the overflow policy
+//     discarded the value
 func (c *Channel[T]) Push(val T) (ok bool) {
 	if c == nil {
 		return
 	}
-	c.acquireLock()
-	defer c.releaseLock()
+	err := c.push(val)
+	if err == ErrFull && c.overflow != int64(OverflowReject) {
+		_, _, err = c.pushOverflow(val)
+	}
+	return err == nil
+}
 
-	if c.tail-c.head >= c.cap {
-		return // Channel is full
+// TryPush is like Push but reports why the value could not be added. It never
+// applies the channel's overflow policy.
+//
+// Returns:
+//   - nil if the value was successfully added
+//   - ErrFull if the channel is full
+//   - ErrClosed if the channel has been closed
+func (c *Channel[T]) TryPush(val T) error {
+	if c == nil {
+		return ErrFull
 	}
-	c.buffer()[c.tail%c.cap] = val
-	c.tail++
-	ok = true
-	return
+	return c.push(val)
+}
+
+// push implements TryPush.
+func (c *Channel[T]) push(val T) error {
+	if c.cap == 0 {
+		// A channel of capacity zero has no slots and is always full.
+		if c.Closed() {
+			return ErrClosed
+		}
+		count(&c.counters().full)
+		return ErrFull
+	}
+	seqs := c.seqs()
+	for attempt := 0; ; attempt++ {
+		tail := atomic.LoadInt64(c.tailp())
+		if tail&closedBit != 0 {
+			return ErrClosed
+		}
+		i := c.index(tail)
+		seq := atomic.LoadInt64(&seqs[i])
+		switch d := seq - 2*tail; {
+		case d == 0:
+			if atomic.CompareAndSwapInt64(c.tailp(), tail, tail+1) {
+				c.stamp(i, tail, false)
+				c.buffer()[i] = val
+				c.seal(i, tail)
+				atomic.StoreInt64(&seqs[i], 2*tail+1)
+				c.observe(tail)
+				c.events().notify()
+				return nil
+			}
+		case d < 0 || seq&abandonedBit != 0:
+			// Slot still holds a value from the previous lap, or was
+			// abandoned by a dead producer and not yet skipped by consumers
+			count(&c.counters().full)
+			return ErrFull
+		}
+		// Another producer claimed the slot first; retry with the new tail
+		count(&c.counters().contention)
+		c.events().backoff(attempt)
+	}
+}
+
+// canPush reports whether push would currently make progress, either by
+// adding a value or by reporting ErrClosed.
+func (c *Channel[T]) canPush() bool {
+	tail := atomic.LoadInt64(c.tailp())
+	if tail&closedBit != 0 {
+		return true
+	}
+	if c.cap == 0 {
+		return false
+	}
+	seq := atomic.LoadInt64(&c.seqs()[c.index(tail)])
+	return seq >= 2*tail && seq&abandonedBit == 0
+}
+
+// PushN attempts to add the values of src to the channel, in order.
+//
+// It reserves as many consecutive free slots as are available, up to len(src),
+// with a single compare-and-swap and fills them with at most two copy calls,
+// one per contiguous span of the ring. Values from one PushN call are never
+// interleaved with values from other producers.
+//
+// Parameters:
+//   - src: The values to add to the channel
+//
+// Returns:
+//   - The number of leading values of src that were added; fewer than len(src)
+//     if the channel filled up, and 0 if it was full or closed
+func (c *Channel[T]) PushN(src []T) int {
+	if c == nil || len(src) == 0 || c.cap == 0 {
+		return 0
+	}
+	seqs := c.seqs()
+	for attempt := 0; ; attempt++ {
+		tail := atomic.LoadInt64(c.tailp())
+		if tail&closedBit != 0 {
+			return 0
+		}
+		k := int64(0)
+		for k < int64(len(src)) && k < c.cap && atomic.LoadInt64(&seqs[c.index(tail+k)]) == 2*(tail+k) {
+			k++
+		}
+		if k == 0 {
+			if seq := atomic.LoadInt64(&seqs[c.index(tail)]); seq < 2*tail || seq&abandonedBit != 0 {
+				count(&c.counters().full)
+				return 0 // Channel is full
+			}
+			count(&c.counters().contention)
+			c.events().backoff(attempt)
+			continue // Another producer claimed the slot first
+		}
+		if atomic.CompareAndSwapInt64(c.tailp(), tail, tail+k) {
+			c.stampN(tail, k, false)
+			copyToRing(c.buffer(), int(c.index(tail)), src[:k])
+			c.sealN(tail, k)
+			for j := range k {
+				atomic.StoreInt64(&seqs[c.index(tail+j)], 2*(tail+j)+1)
+			}
+			c.observe(tail + k - 1)
+			c.events().notify()
+			return int(k)
+		}
+		count(&c.counters().contention)
+		c.events().backoff(attempt)
+	}
+}
+
+// PushWait adds a value to the channel, waiting for free space if it is full.
+//
+// The calling goroutine spins and yields until a Pop makes room or ctx is done;
+// it does not allocate while waiting. Like sending on a nil built-in channel,
+// PushWait on a nil *Channel waits until ctx is done.
+//
+// Parameters:
+//   - ctx: Context that bounds the wait
+//   - val: The value to add to the channel
+//
+// Returns:
+//   - nil if the value was added
+//   - ErrClosed if the channel is or becomes closed while waiting
+//   - ctx.Err() if ctx was done before space became available
+func (c *Channel[T]) PushWait(ctx context.Context, val T) error {
+	if c == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := c.push(val); err != ErrFull {
+		return err
+	}
+	return pushWait(ctx, c, val)
 }
 
 // Pop attempts to remove and return a value from the channel.
 //
 // The operation is atomic and thread-safe. If the channel is empty,
 // the operation returns immediately with the zero value and false.
+// Values pushed before Close remain available to Pop until drained.
 //
 // Returns:
 //   - v: The value removed from the channel, or zero value of T if empty
 //   - ok: true if a value was successfully removed, false if the channel was empty
-//
-// The function automatically resets internal head/tail pointers when the channel
-// becomes empty to prevent potential overflow in long-running applications.
 func (c *Channel[T]) Pop() (v T, ok bool) {
 	if c == nil {
 		return
 	}
-	c.acquireLock()
-	defer c.releaseLock()
-	if c.tail == c.head {
-		return // Channel is empty
+	v, err := c.pop()
+	ok = err == nil
+	return
+}
+
+// TryPop is like Pop but distinguishes a channel that is merely empty from one
+// that has been closed and drained.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrEmpty if the channel is empty but still open,
+//     or ErrClosed if the channel is closed and no values remain
+func (c *Channel[T]) TryPop() (v T, err error) {
+	if c == nil {
+		err = ErrEmpty
+		return
 	}
-	ok = true
-	v = c.buffer()[c.head%c.cap]
-	c.head++
-	if c.head == c.tail {
-		c.head, c.tail = 0, 0 // Reset to prevent overflow
+	return c.pop()
+}
+
+// pop implements TryPop.
+func (c *Channel[T]) pop() (v T, err error) {
+	if c.cap == 0 {
+		// A channel of capacity zero has no slots and is always empty.
+		err = ErrEmpty
+		if c.Closed() {
+			err = ErrClosed
+		}
+		count(&c.counters().empty)
+		return
+	}
+	seqs := c.seqs()
+	for attempt := 0; ; attempt++ {
+		head := atomic.LoadInt64(c.headp())
+		i := c.index(head)
+		seq := atomic.LoadInt64(&seqs[i])
+		switch d := seq - (2*head + 1); {
+		case d == 0:
+			if atomic.CompareAndSwapInt64(c.headp(), head, head+1) {
+				c.stamp(i, head, true)
+				v = c.buffer()[i]
+				atomic.StoreInt64(&seqs[i], 2*(head+c.cap))
+				c.events().notify()
+				return
+			}
+		case d < 0:
+			// Nothing published at head. The channel is drained for good only
+			// if it is closed and no producer holds a claimed slot.
+			if tail := atomic.LoadInt64(c.tailp()); tail&closedBit != 0 && tail&^closedBit == head {
+				err = ErrClosed
+			} else {
+				err = ErrEmpty // Channel is empty
+			}
+			count(&c.counters().empty)
+			return
+		case seq == abandoned(head):
+			c.skip(head)
+			continue
+		}
+		// Another consumer claimed the slot first; retry with the new head
+		count(&c.counters().contention)
+		c.events().backoff(attempt)
+	}
+}
+
+// canPop reports whether pop would currently make progress, either by
+// removing a value or by reporting ErrClosed.
+func (c *Channel[T]) canPop() bool {
+	if c.cap == 0 {
+		return c.Closed()
+	}
+	head := atomic.LoadInt64(c.headp())
+	if atomic.LoadInt64(&c.seqs()[c.index(head)]) >= 2*head+1 {
+		return true
+	}
+	tail := atomic.LoadInt64(c.tailp())
+	return tail&closedBit != 0 && tail&^closedBit == head
+}
+
+// PopN attempts to remove values from the channel into dst, in order.
+//
+// It claims as many consecutive published values as are available, up to
+// len(dst), with a single compare-and-swap and copies them out with at most two
+// copy calls, one per contiguous span of the ring.
+//
+// Parameters:
+//   - dst: Destination for the removed values
+//
+// Returns:
+//   - The number of values written to the front of dst; fewer than len(dst)
+//     if the channel ran empty, and 0 if it was empty
+func (c *Channel[T]) PopN(dst []T) int {
+	if c == nil || len(dst) == 0 || c.cap == 0 {
+		return 0
+	}
+	seqs := c.seqs()
+	for attempt := 0; ; attempt++ {
+		head := atomic.LoadInt64(c.headp())
+		k := int64(0)
+		for k < int64(len(dst)) && k < c.cap && atomic.LoadInt64(&seqs[c.index(head+k)]) == 2*(head+k)+1 {
+			k++
+		}
+		if k == 0 {
+			seq := atomic.LoadInt64(&seqs[c.index(head)])
+			if seq < 2*head+1 {
+				count(&c.counters().empty)
+				return 0 // Channel is empty
+			}
+			if seq == abandoned(head) {
+				c.skip(head)
+				continue
+			}
+			count(&c.counters().contention)
+			c.events().backoff(attempt)
+			continue // Another consumer claimed the slot first
+		}
+		if atomic.CompareAndSwapInt64(c.headp(), head, head+k) {
+			c.stampN(head, k, true)
+			copyFromRing(dst[:k], c.buffer(), int(c.index(head)))
+			for j := range k {
+				atomic.StoreInt64(&seqs[c.index(head+j)], 2*(head+j+c.cap))
+			}
+			c.events().notify()
+			return int(k)
+		}
+		count(&c.counters().contention)
+		c.events().backoff(attempt)
 	}
-	return
+}
+
+// PopWait removes and returns a value from the channel, waiting for one to
+// arrive if it is empty.
+//
+// The calling goroutine spins and yields until a Push makes a value available
+// or ctx is done; it does not allocate while waiting. Like receiving from a nil
+// built-in channel, PopWait on a nil *Channel waits until ctx is done.
+//
+// Looping on PopWait until it returns ErrClosed mirrors ranging over a
+// built-in channel:
+//
+//	for {
+//		v, err := ch.PopWait(ctx)
+//		if err != nil {
+//			break // ErrClosed once the producer is done and the channel is drained
+//		}
+//		handle(v)
+//	}
+//
+// Parameters:
+//   - ctx: Context that bounds the wait
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrClosed if the channel is closed and drained,
+//     or ctx.Err() if ctx was done before a value arrived
+func (c *Channel[T]) PopWait(ctx context.Context) (v T, err error) {
+	if c == nil {
+		<-ctx.Done()
+		err = ctx.Err()
+		return
+	}
+	if v, err = c.pop(); err != ErrEmpty {
+		return
+	}
+	return popWait(ctx, c)
+}
+
+// Close marks the channel as closed.
+//
+// After Close, Push fails and TryPush and PushWait return ErrClosed. Values
+// already in the channel can still be popped; once they are drained, TryPop and
+// PopWait return ErrClosed. Goroutines blocked in PushWait or PopWait are woken.
+//
+// Returns:
+//   - nil if the channel was closed by this call
+//   - ErrClosed if the channel had already been closed
+func (c *Channel[T]) Close() error {
+	if c == nil {
+		return ErrClosed
+	}
+	for {
+		tail := atomic.LoadInt64(c.tailp())
+		if tail&closedBit != 0 {
+			return ErrClosed
+		}
+		if atomic.CompareAndSwapInt64(c.tailp(), tail, tail|closedBit) {
+			c.events().wake()
+			return nil
+		}
+	}
+}
+
+// Closed reports whether Close has been called on the channel.
+func (c *Channel[T]) Closed() bool {
+	if c == nil {
+		return false
+	}
+	return atomic.LoadInt64(c.tailp())&closedBit != 0
 }
 
 // Len returns the current number of elements stored in the channel.
 //
 // This operation is thread-safe and provides a snapshot of the channel's
 // length at the time of the call. The actual length may change immediately
-// after the function returns due to concurrent operations.
+// after the function returns due to concurrent operations. Slots claimed by
+// producers that have not finished writing are counted.
 //
 // Returns:
 //   - The number of elements currently in the channel (0 to Cap())
@@ -209,14 +717,10 @@ func (c *Channel[T]) Len() int {
 	if c == nil {
 		return 0
 	}
-
-	c.acquireLock()
-	defer c.releaseLock()
-
-	if c.tail >= c.head {
-		return int(c.tail - c.head)
-	}
-	return int(c.cap - c.head + c.tail)
+	// Load head first: tail only grows, so it can never appear behind it.
+	head := atomic.LoadInt64(c.headp())
+	tail := atomic.LoadInt64(c.tailp()) &^ closedBit
+	return int(min(tail-head, c.cap))
 }
 
 // Cap returns the maximum capacity of the channel.
@@ -18,14 +18,40 @@
 package xxchan_test
 
 import (
+	"context"
+	"fmt"
+	"runtime"
 	"sync"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/smasher164/mem"
 	"github.com/stretchr/testify/require"
 	"go.yuchanns.xyz/xxchan"
 )
 
+// allocations maps the blocks returned by alloc to the ones mem.Alloc returned.
+var allocations sync.Map // unsafe.Pointer -> unsafe.Pointer
+
+// alloc is mem.Alloc with the block aligned to 16 bytes, as arenas require;
+// channels need 8. mem.Alloc only guarantees the alignment of a pointer, which
+// is 4 bytes on 32-bit platforms such as 386. Sizes are rounded up to 8 as
+// well, so that mem.Alloc never splits its free list at a misaligned address.
+func alloc(size uint) unsafe.Pointer {
+	raw := mem.Alloc((size+7)&^7 + 16)
+	ptr := unsafe.Add(raw, -int(uintptr(raw))&15)
+	allocations.Store(ptr, raw)
+	return ptr
+}
+
+// free releases a block returned by alloc.
+func free(ptr unsafe.Pointer) {
+	if raw, ok := allocations.LoadAndDelete(ptr); ok {
+		mem.Free(raw.(unsafe.Pointer))
+	}
+}
+
 func TestChannelFull(t *testing.T) {
 	t.Parallel()
 
@@ -34,9 +60,9 @@ func TestChannelFull(t *testing.T) {
 	n := 10
 	length := n / 2
 
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int](length)))
+	ptr := alloc(uint(xxchan.Sizeof[int](length)))
 	t.Cleanup(func() {
-		mem.Free(ptr)
+		free(ptr)
 	})
 
 	ch := xxchan.Make[int](ptr, length)
@@ -70,6 +96,44 @@ func TestChannelFull(t *testing.T) {
 	assert.Equal(0, ch.Len())
 }
 
+func TestChannelZeroCapacity(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.Sizeof[int](0)))
+	t.Cleanup(func() { free(ptr) })
+
+	// A channel without slots behaves as it always did: full and empty at
+	// once, rather than dividing by its capacity.
+	ch := xxchan.Make[int](ptr, 0)
+	assert.Equal(0, ch.Cap())
+	attached, err := xxchan.Attach[int](ptr)
+	assert.NoError(err)
+	assert.Equal(0, attached.Cap())
+	mpsc, err := xxchan.AttachMPSC[int](ptr)
+	assert.NoError(err)
+	assert.Equal(0, mpsc.Cap())
+
+	assert.False(ch.Push(1))
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrFull)
+	assert.Equal(0, ch.PushN([]int{1, 2}))
+	_, ok := ch.Pop()
+	assert.False(ok)
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrEmpty)
+	assert.Equal(0, ch.PopN(make([]int, 2)))
+	assert.Equal(0, ch.Len())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(ch.PushWait(ctx, 1), context.DeadlineExceeded)
+
+	assert.NoError(ch.Close())
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrClosed)
+	_, err = ch.PopWait(context.Background())
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
 func TestChannelConcurrent(t *testing.T) {
 	t.Parallel()
 
@@ -77,9 +141,9 @@ func TestChannelConcurrent(t *testing.T) {
 
 	n := 10
 
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int](n)))
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
 	t.Cleanup(func() {
-		mem.Free(ptr)
+		free(ptr)
 	})
 
 	ch := xxchan.Make[int](ptr, n)
@@ -123,9 +187,9 @@ func TestChannelInt32(t *testing.T) {
 
 	n := 10
 
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int32](n)))
+	ptr := alloc(uint(xxchan.Sizeof[int32](n)))
 	t.Cleanup(func() {
-		mem.Free(ptr)
+		free(ptr)
 	})
 
 	ch := xxchan.Make[int32](ptr, n)
@@ -155,8 +219,8 @@ func TestChannelInt(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[int](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -180,8 +244,8 @@ func TestChannelInt8(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int8](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[int8](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[int8](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -205,8 +269,8 @@ func TestChannelInt16(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int16](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[int16](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[int16](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -230,8 +294,8 @@ func TestChannelInt64(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[int64](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[int64](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[int64](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -255,8 +319,8 @@ func TestChannelUint(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[uint](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[uint](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[uint](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -280,8 +344,8 @@ func TestChannelUint8(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[uint8](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[uint8](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[uint8](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -305,8 +369,8 @@ func TestChannelUint16(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[uint16](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[uint16](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[uint16](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -330,8 +394,8 @@ func TestChannelUint32(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[uint32](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[uint32](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[uint32](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -355,8 +419,8 @@ func TestChannelUint64(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[uint64](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[uint64](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[uint64](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -380,8 +444,8 @@ func TestChannelFloat32(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[float32](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[float32](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[float32](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -405,8 +469,8 @@ func TestChannelFloat64(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 10
-	ptr := mem.Alloc(uint(xxchan.Sizeof[float64](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[float64](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[float64](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -430,8 +494,8 @@ func TestChannelBool(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[bool](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[bool](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[bool](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -453,8 +517,8 @@ func TestChannelString(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[string](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[string](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[string](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -476,8 +540,8 @@ func TestChannelComplex64(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[complex64](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[complex64](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[complex64](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -499,8 +563,8 @@ func TestChannelComplex128(t *testing.T) {
 	t.Parallel()
 	assert := require.New(t)
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[complex128](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[complex128](n)))
+	t.Cleanup(func() { free(ptr) })
 	ch := xxchan.Make[complex128](ptr, n)
 	assert.NotNil(ch)
 	assert.Equal(n, ch.Cap())
@@ -528,8 +592,8 @@ func TestChannelStruct(t *testing.T) {
 	}
 
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[MyStruct](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[MyStruct](n)))
+	t.Cleanup(func() { free(ptr) })
 
 	ch := xxchan.Make[MyStruct](ptr, n)
 	assert.NotNil(ch)
@@ -560,8 +624,8 @@ func TestChannelPointer(t *testing.T) {
 	assert := require.New(t)
 
 	n := 2
-	ptr := mem.Alloc(uint(xxchan.Sizeof[*int](n)))
-	t.Cleanup(func() { mem.Free(ptr) })
+	ptr := alloc(uint(xxchan.Sizeof[*int](n)))
+	t.Cleanup(func() { free(ptr) })
 
 	ch := xxchan.Make[*int](ptr, n)
 	assert.NotNil(ch)
@@ -586,3 +650,378 @@ func TestChannelPointer(t *testing.T) {
 	_, ok = ch.Pop()
 	assert.False(ok)
 }
+
+func TestChannelWait(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	total := 1000
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	ctx := context.Background()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range total {
+			assert.NoError(ch.PushWait(ctx, i))
+		}
+	}()
+
+	for i := range total {
+		val, err := ch.PopWait(ctx)
+		assert.NoError(err)
+		assert.Equal(i, val)
+	}
+	wg.Wait()
+	assert.Equal(0, ch.Len())
+}
+
+func TestChannelWaitCanceled(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := ch.PopWait(ctx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	assert.True(ch.Push(1))
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(ch.PushWait(ctx, 2), context.Canceled)
+	assert.Equal(1, ch.Len())
+
+	var nilCh *xxchan.Channel[int]
+	_, err = nilCh.PopWait(ctx)
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestChannelWaitAllocs(t *testing.T) {
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = ch.PushWait(context.Background(), 1)
+		// The channel is full now, so this goes through the wait path.
+		_ = ch.PushWait(ctx, 2)
+		_, _ = ch.PopWait(context.Background())
+	})
+	assert.Equal(0.0, allocs)
+}
+
+func TestChannelClose(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	assert.False(ch.Closed())
+
+	assert.True(ch.Push(1))
+	assert.True(ch.Push(2))
+	assert.NoError(ch.Close())
+	assert.True(ch.Closed())
+	assert.ErrorIs(ch.Close(), xxchan.ErrClosed)
+
+	assert.False(ch.Push(3))
+	assert.ErrorIs(ch.TryPush(3), xxchan.ErrClosed)
+	assert.ErrorIs(ch.PushWait(context.Background(), 3), xxchan.ErrClosed)
+	assert.Equal(2, ch.Len())
+
+	val, err := ch.TryPop()
+	assert.NoError(err)
+	assert.Equal(1, val)
+	val, err = ch.PopWait(context.Background())
+	assert.NoError(err)
+	assert.Equal(2, val)
+
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+	_, err = ch.PopWait(context.Background())
+	assert.ErrorIs(err, xxchan.ErrClosed)
+	_, ok := ch.Pop()
+	assert.False(ok)
+}
+
+func TestChannelTryErrors(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	_, err := ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrEmpty)
+	assert.NoError(ch.TryPush(1))
+	assert.ErrorIs(ch.TryPush(2), xxchan.ErrFull)
+}
+
+func TestChannelCloseDrain(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	total := 1000
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	ctx := context.Background()
+
+	go func() {
+		for i := range total {
+			if err := ch.PushWait(ctx, i); err != nil {
+				panic(err)
+			}
+		}
+		_ = ch.Close()
+	}()
+
+	results := make(chan int, 4)
+	for range 4 {
+		go func() {
+			count := 0
+			for {
+				if _, err := ch.PopWait(ctx); err != nil {
+					assert.ErrorIs(err, xxchan.ErrClosed)
+					break
+				}
+				count++
+			}
+			results <- count
+		}()
+	}
+
+	sum := 0
+	for range 4 {
+		sum += <-results
+	}
+	assert.Equal(total, sum)
+}
+
+func TestChannelMPMC(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 16
+	producers := 4
+	consumers := 4
+	perProducer := 10000
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	ctx := context.Background()
+
+	wg := &sync.WaitGroup{}
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				if err := ch.PushWait(ctx, p*perProducer+i); err != nil {
+					panic(err)
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		_ = ch.Close()
+	}()
+
+	seen := make([][]int, consumers)
+	cwg := &sync.WaitGroup{}
+	for c := range consumers {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			for {
+				v, err := ch.PopWait(ctx)
+				if err != nil {
+					return
+				}
+				seen[c] = append(seen[c], v)
+			}
+		}()
+	}
+	cwg.Wait()
+
+	counts := make([]int, producers*perProducer)
+	for _, vals := range seen {
+		last := make([]int, producers)
+		for i := range last {
+			last[i] = -1
+		}
+		for _, v := range vals {
+			counts[v]++
+			// Values from one producer reach one consumer in order.
+			p, i := v/perProducer, v%perProducer
+			assert.Greater(i, last[p])
+			last[p] = i
+		}
+	}
+	for _, c := range counts {
+		assert.Equal(1, c)
+	}
+}
+
+func TestChannelBatch(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 5
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	// Move head and tail to the middle of the ring so batches wrap around.
+	assert.Equal(3, ch.PushN([]int{-1, -2, -3}))
+	assert.Equal(3, ch.PopN(make([]int, 3)))
+
+	assert.Equal(4, ch.PushN([]int{0, 1, 2, 3}))
+	assert.Equal(1, ch.PushN([]int{4, 5, 6}))
+	assert.Equal(0, ch.PushN([]int{5}))
+	assert.Equal(n, ch.Len())
+
+	dst := make([]int, 3)
+	assert.Equal(3, ch.PopN(dst))
+	assert.Equal([]int{0, 1, 2}, dst)
+	val, ok := ch.Pop()
+	assert.True(ok)
+	assert.Equal(3, val)
+	dst = make([]int, 4)
+	assert.Equal(1, ch.PopN(dst))
+	assert.Equal(4, dst[0])
+	assert.Equal(0, ch.PopN(dst))
+	assert.Equal(0, ch.PushN(nil))
+
+	assert.NoError(ch.Close())
+	assert.Equal(0, ch.PushN([]int{1}))
+}
+
+func TestChannelBatchConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 16
+	producers := 4
+	perProducer := 10000
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	wg := &sync.WaitGroup{}
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			src := make([]int, perProducer)
+			for i := range src {
+				src[i] = p*perProducer + i
+			}
+			for len(src) > 0 {
+				k := ch.PushN(src[:min(len(src), 7)])
+				if k == 0 {
+					runtime.Gosched()
+				}
+				src = src[k:]
+			}
+		}()
+	}
+
+	counts := make([]int, producers*perProducer)
+	dst := make([]int, 5)
+	for got := 0; got < len(counts); {
+		k := ch.PopN(dst)
+		if k == 0 {
+			runtime.Gosched()
+		}
+		for _, v := range dst[:k] {
+			counts[v]++
+		}
+		got += k
+	}
+	wg.Wait()
+	for _, c := range counts {
+		assert.Equal(1, c)
+	}
+}
+
+func TestChannelCapacityIndexing(t *testing.T) {
+	t.Parallel()
+
+	// Power-of-two capacities index slots with a mask, the others with a
+	// division; both must wrap the free-running positions the same way.
+	for _, n := range []int{1, 2, 6, 8, 100, 128} {
+		t.Run(fmt.Sprintf("cap-%d", n), func(t *testing.T) {
+			t.Parallel()
+			assert := require.New(t)
+
+			ptr := alloc(uint(xxchan.Sizeof[int](n)))
+			t.Cleanup(func() { free(ptr) })
+			sptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+			t.Cleanup(func() { free(sptr) })
+
+			ch := xxchan.Make[int](ptr, n)
+			spsc := xxchan.MakeSPSC[int](sptr, n)
+			next, want := 0, 0
+			for lap := 0; lap < 5; lap++ {
+				// Leave the ring partly filled so that every lap starts at a
+				// different slot.
+				for range n/2 + 1 {
+					assert.True(ch.Push(next))
+					assert.True(spsc.Push(next))
+					next++
+				}
+				v, ok := ch.PeekAt(0)
+				assert.True(ok)
+				assert.Equal(want, v)
+				for range n/2 + 1 {
+					v, ok := ch.Pop()
+					assert.True(ok)
+					assert.Equal(want, v)
+					v, ok = spsc.Pop()
+					assert.True(ok)
+					assert.Equal(want, v)
+					want++
+				}
+			}
+
+			src := make([]int, n)
+			for i := range src {
+				src[i] = next + i
+			}
+			assert.Equal(n, ch.PushN(src))
+			assert.False(ch.Push(-1))
+			dst := make([]int, n)
+			assert.Equal(n, ch.PopN(dst))
+			assert.Equal(src, dst)
+		})
+	}
+}
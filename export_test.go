@@ -0,0 +1,99 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// ClaimPush claims and stamps the next slot of c for a push, then returns
+// without publishing a value, leaving the channel as a producer killed halfway
+// through Push would.
+func ClaimPush[T any](c *Channel[T]) bool {
+	tail := atomic.LoadInt64(c.tailp())
+	if atomic.LoadInt64(&c.seqs()[c.index(tail)]) != 2*tail || !atomic.CompareAndSwapInt64(c.tailp(), tail, tail+1) {
+		return false
+	}
+	c.stamp(c.index(tail), tail, false)
+	return true
+}
+
+// ClaimPop claims and stamps the slot at the head of c for a pop, then returns
+// without releasing it, leaving the channel as a consumer killed halfway
+// through Pop would.
+func ClaimPop[T any](c *Channel[T]) bool {
+	head := atomic.LoadInt64(c.headp())
+	if atomic.LoadInt64(&c.seqs()[c.index(head)]) != 2*head+1 || !atomic.CompareAndSwapInt64(c.headp(), head, head+1) {
+		return false
+	}
+	c.stamp(c.index(head), head, true)
+	return true
+}
+
+// ClaimPopMPSC claims and stamps the slot at the head of c for a pop the way
+// MPSC[T].Pop does, then returns without releasing it, leaving the channel as
+// a consumer killed halfway through Pop would.
+func ClaimPopMPSC[T any](c *MPSC[T]) bool {
+	ch := c.mpmc()
+	head := atomic.LoadInt64(ch.headp())
+	if atomic.LoadInt64(&ch.seqs()[ch.index(head)]) != 2*head+1 {
+		return false
+	}
+	ch.stamp(ch.index(head), head, true)
+	atomic.StoreInt64(ch.headp(), head+1)
+	return true
+}
+
+// SlotOffsets returns the offsets from the start of c of the head position
+// and of the sequence number, checksum and value of the slot holding position
+// pos, for tests that damage a persistent channel's file.
+func SlotOffsets[T any](c *Channel[T], pos int64) (head, seq, sum, val int64) {
+	base := uintptr(unsafe.Pointer(c))
+	i := c.index(pos)
+	head = int64(uintptr(unsafe.Pointer(c.headp())) - base)
+	seq = int64(uintptr(unsafe.Pointer(&c.seqs()[i])) - base)
+	sum = int64(uintptr(unsafe.Pointer(&c.checksums()[i])) - base)
+	val = int64(uintptr(unsafe.Pointer(&c.buffer()[i])) - base)
+	return
+}
+
+// SPSCOffsets returns the offsets from the start of c of the fields written
+// by the producer (tail, headCache) and by the consumer (head, tailCache).
+func SPSCOffsets[T any](c *SPSC[T]) (tail, headCache, head, tailCache int64) {
+	return int64(unsafe.Offsetof(c.tail)), int64(unsafe.Offsetof(c.headCache)),
+		int64(unsafe.Offsetof(c.head)), int64(unsafe.Offsetof(c.tailCache))
+}
+
+// ReserveNamed claims the entry for name in r and leaves it reserved, as a
+// process killed halfway through CreateNamed would.
+func ReserveNamed(r *Registry, name string) error {
+	_, err := r.reserve(name)
+	return err
+}
+
+// NamedOwner returns the process recorded as creating the channel named name
+// in r, or 0 if there is none.
+func NamedOwner(r *Registry, name string) int64 {
+	for i := range r.table() {
+		if e := &r.table()[i]; atomic.LoadUint64(&e.key) == registryKey(name) {
+			return atomic.LoadInt64(&e.owner)
+		}
+	}
+	return 0
+}
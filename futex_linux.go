@@ -0,0 +1,67 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package xxchan
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Futex operations, from <linux/futex.h>. The private variants tell the
+// kernel that the word is only shared between threads of this process; the
+// plain ones key the word by the page it lives on, so that every process
+// mapping that page waits on and wakes the same futex.
+const (
+	futexWaitShared  = 0   // FUTEX_WAIT
+	futexWakeShared  = 1   // FUTEX_WAKE
+	futexWaitPrivate = 128 // FUTEX_WAIT | FUTEX_PRIVATE_FLAG
+	futexWakePrivate = 129 // FUTEX_WAKE | FUTEX_PRIVATE_FLAG
+)
+
+// futexWait sleeps until addr is woken by futexWake, the timeout expires or a
+// signal arrives, provided *addr still equals val. A zero timeout waits
+// without a deadline. Spurious wake-ups are possible; callers re-check.
+// Waits with shared set are woken by futexWake calls from any process that
+// maps the same memory, and only by those with shared set.
+func futexWait(addr *int32, val int32, timeout time.Duration, shared bool) {
+	var ts *syscall.Timespec
+	if timeout > 0 {
+		t := syscall.NsecToTimespec(int64(timeout))
+		ts = &t
+	}
+	op := uintptr(futexWaitPrivate)
+	if shared {
+		op = futexWaitShared
+	}
+	_, _, _ = syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), op,
+		uintptr(uint32(val)), uintptr(unsafe.Pointer(ts)), 0, 0)
+}
+
+// futexWake wakes every thread sleeping in futexWait on addr with the same
+// value of shared.
+func futexWake(addr *int32, shared bool) {
+	op := uintptr(futexWakePrivate)
+	if shared {
+		op = futexWakeShared
+	}
+	_, _, _ = syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), op,
+		uintptr(1<<31-1), 0, 0, 0)
+}
@@ -0,0 +1,113 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package xxchan_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+// waitProcess waits for the named child process to exit, failing the test if
+// it takes longer than timeout, as it would if a wake-up never reached it.
+func waitProcess(t *testing.T, name string, cmd *exec.Cmd, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("%s did not exit within %s", name, timeout)
+		return nil
+	}
+}
+
+func TestFutexSharedCrossProcess(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	total := 5000
+
+	// A tiny ring makes the producer block on a full channel as often as the
+	// consumer blocks on an empty one.
+	sh, err := xxchan.Create[int64](path, 4, xxchan.WithLockStrategy(xxchan.LockFutexShared))
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	env := []string{"XXCHAN_FUTEX_PATH=" + path, "XXCHAN_FUTEX_TOTAL=" + strconv.Itoa(total)}
+	consumer := helperProcess(t, "TestFutexSharedProcess", append(env, "XXCHAN_FUTEX_ROLE=consumer")...)
+	assert.NoError(consumer.Start())
+	t.Cleanup(func() { _ = consumer.Process.Kill() })
+
+	// Give the consumer time to fall asleep in the kernel on the empty
+	// channel, so that the first push has to wake it from another process.
+	time.Sleep(100 * time.Millisecond)
+	producer := helperProcess(t, "TestFutexSharedProcess", append(env, "XXCHAN_FUTEX_ROLE=producer")...)
+	assert.NoError(producer.Start())
+	t.Cleanup(func() { _ = producer.Process.Kill() })
+
+	assert.NoError(waitProcess(t, "producer", producer, time.Minute))
+	assert.NoError(waitProcess(t, "consumer", consumer, time.Minute))
+	assert.True(sh.Closed())
+	assert.Equal(uint64(total), sh.Stats().Pops)
+}
+
+// TestFutexSharedProcess runs in the child processes started by
+// TestFutexSharedCrossProcess. Both wait without a deadline, so the futex
+// sleeps never time out and every wake-up has to come from the other process.
+func TestFutexSharedProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_FUTEX_PATH")
+	if path == "" {
+		t.Skip("helper process for TestFutexSharedCrossProcess")
+	}
+	assert := require.New(t)
+	total, err := strconv.Atoi(os.Getenv("XXCHAN_FUTEX_TOTAL"))
+	assert.NoError(err)
+
+	sh, err := xxchan.Open[int64](path)
+	assert.NoError(err)
+	defer sh.Unmap()
+
+	ctx := context.Background()
+	switch os.Getenv("XXCHAN_FUTEX_ROLE") {
+	case "producer":
+		for i := range total {
+			assert.NoError(sh.PushWait(ctx, int64(i)))
+		}
+		assert.NoError(sh.Close())
+	case "consumer":
+		for i := 0; ; i++ {
+			v, err := sh.PopWait(ctx)
+			if err != nil {
+				assert.ErrorIs(err, xxchan.ErrClosed)
+				assert.Equal(total, i)
+				return
+			}
+			assert.Equal(int64(i), v)
+		}
+	}
+}
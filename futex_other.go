@@ -0,0 +1,35 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build !linux
+
+package xxchan
+
+import (
+	"runtime"
+	"time"
+)
+
+// futexWait yields the processor, since futexes are Linux-only; LockFutex
+// and LockFutexShared behave like LockAdaptive elsewhere.
+func futexWait(addr *int32, val int32, timeout time.Duration, shared bool) {
+	runtime.Gosched()
+}
+
+// futexWake does nothing; waiters yielding in futexWait notice the change
+// themselves.
+func futexWake(addr *int32, shared bool) {}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrPointerElement is returned when a channel would store values containing
+// Go pointers in memory the garbage collector does not scan.
+//
+// Such memory includes blocks from C allocators or mmap and the []byte buffers
+// passed to New. The collector cannot see the pointers stored there, so the
+// objects they refer to may be freed or moved while the values are still
+// queued.
+var ErrPointerElement = errors.New("xxchan: element type contains Go pointers")
+
+// AllowPointers disables the check that rejects element types containing Go
+// pointers, strings, slices, maps, channels, interfaces or funcs.
+//
+// Use it only when the backing memory is scanned by the garbage collector, for
+// example a []T or a struct field reinterpreted as a channel buffer, or when
+// every value pushed refers to memory that is kept alive by other means.
+func AllowPointers() Option {
+	return func(o *options) { o.allowPointers = true }
+}
+
+// pointerPaths caches pointerPath results per element type.
+var pointerPaths sync.Map // reflect.Type -> string
+
+// checkPointerFree returns an ErrPointerElement naming the first part of T that
+// holds a Go pointer, or nil if T is safe to store in unscanned memory.
+func checkPointerFree[T any]() error {
+	t := reflect.TypeFor[T]()
+	path, ok := pointerPaths.Load(t)
+	if !ok {
+		path = pointerPath(t, t.String())
+		pointerPaths.Store(t, path)
+	}
+	if path != "" {
+		return fmt.Errorf("%w: %s", ErrPointerElement, path)
+	}
+	return nil
+}
+
+// pointerPath walks t and describes the first field or element whose kind the
+// garbage collector would need to trace, such as "pkg.Event.Tags[0] (string)".
+// It returns an empty string if t holds no pointers.
+func pointerPath(t reflect.Type, name string) string {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.String, reflect.Slice,
+		reflect.Map, reflect.Chan, reflect.Interface, reflect.Func:
+		return name + " (" + t.String() + ")"
+	case reflect.Array:
+		if t.Len() == 0 {
+			return ""
+		}
+		return pointerPath(t.Elem(), name+"[0]")
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if path := pointerPath(f.Type, name+"."+f.Name); path != "" {
+				return path
+			}
+		}
+	}
+	return ""
+}
@@ -5,11 +5,11 @@ go 1.24.4
 require (
 	github.com/smasher164/mem v0.0.0-20200311200026-6e9ed23f934d
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/sys v0.30.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20191113165036-4c7a9d0fe056 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,222 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// headerMagic identifies a memory block initialized by this package ("xxch").
+	headerMagic uint32 = 0x78786368
+
+	// layoutVersion is bumped whenever the in-memory layout of a channel
+	// changes, so that a block written by one release is not misread by another.
+	layoutVersion uint16 = 9
+)
+
+// Layout kinds recorded in the header, telling apart channel flavours whose
+// blocks are not interchangeable.
+const (
+	layoutMPMC         uint16 = iota + 1 // Channel[T] and MPSC[T]
+	layoutSPSC                           // SPSC[T]
+	layoutBytes                          // ByteChannel
+	layoutBroadcast                      // Broadcast[T]
+	layoutRegistry                       // Registry
+	layoutArena                          // Arena
+	layoutArenaChannel                   // ArenaChannel
+)
+
+var (
+	// ErrNotChannel is returned by Attach when the memory block does not start
+	// with a channel header.
+	ErrNotChannel = errors.New("xxchan: memory does not hold a channel")
+
+	// ErrLayoutMismatch is returned by Attach when the block was written with a
+	// different layout version or for a different channel flavour.
+	ErrLayoutMismatch = errors.New("xxchan: channel layout mismatch")
+
+	// ErrTypeMismatch is returned by Attach when the block was created for a
+	// different element type.
+	ErrTypeMismatch = errors.New("xxchan: channel element type mismatch")
+)
+
+// header is the self-describing prefix of every channel block. It records
+// enough about how the block was created for Attach to refuse a block that
+// would otherwise be silently misinterpreted.
+//
+// Every channel embeds header first, so its marker field raises the alignment
+// of the channel structs to 8 bytes even where int64 is only 4-byte aligned, as
+// on 386 and 32-bit ARM. The fields that follow are laid out so that every
+// 64-bit word accessed atomically sits at a multiple of 8 from the start of the
+// block, which must therefore be 8-byte aligned itself.
+type header struct {
+	_           [0]atomic.Int64 // Forces 8-byte alignment on 32-bit platforms
+	magic       uint32
+	version     uint16
+	layout      uint16
+	elemSize    uint32
+	elemAlign   uint32
+	fingerprint uint64
+}
+
+// init fills in the header for a block holding elements of type T. The magic
+// number is stored last and atomically, so that a concurrent Attach from
+// another process never accepts a half-written header.
+func (h *header) init(layout uint16, elemSize, elemAlign uintptr, fingerprint uint64) {
+	h.version = layoutVersion
+	h.layout = layout
+	h.elemSize = uint32(elemSize)
+	h.elemAlign = uint32(elemAlign)
+	h.fingerprint = fingerprint
+	atomic.StoreUint32(&h.magic, headerMagic)
+}
+
+// check verifies that the header describes a block of the given layout holding
+// elements of type T.
+func (h *header) check(layout uint16, elemSize, elemAlign uintptr, fingerprint uint64) error {
+	if magic := atomic.LoadUint32(&h.magic); magic != headerMagic {
+		return fmt.Errorf("%w: bad magic %#x", ErrNotChannel, magic)
+	}
+	if h.version != layoutVersion || h.layout != layout {
+		return fmt.Errorf("%w: block has layout %d version %d, want layout %d version %d",
+			ErrLayoutMismatch, h.layout, h.version, layout, layoutVersion)
+	}
+	if uintptr(h.elemSize) != elemSize || uintptr(h.elemAlign) != elemAlign {
+		return fmt.Errorf("%w: block holds %d-byte elements aligned to %d, want %d aligned to %d",
+			ErrTypeMismatch, h.elemSize, h.elemAlign, elemSize, elemAlign)
+	}
+	if h.fingerprint != fingerprint {
+		return fmt.Errorf("%w: fingerprint %#x, want %#x", ErrTypeMismatch, h.fingerprint, fingerprint)
+	}
+	return nil
+}
+
+// fingerprints caches typeFingerprint results, so that Make stays
+// allocation-free after the first channel of each element type.
+var fingerprints sync.Map // reflect.Type -> uint64
+
+// fingerprintOf returns the structural fingerprint of T.
+func fingerprintOf[T any]() uint64 {
+	t := reflect.TypeFor[T]()
+	if fp, ok := fingerprints.Load(t); ok {
+		return fp.(uint64)
+	}
+	h := fnv.New64a()
+	writeTypeShape(h, t)
+	fp := h.Sum64()
+	fingerprints.Store(t, fp)
+	return fp
+}
+
+// writeTypeShape writes a description of the memory layout of t: its kind and
+// size and, for arrays and structs, the layout of their elements and fields.
+// Type names are left out on purpose, so that two programs declaring the same
+// struct in different packages can still share a channel.
+func writeTypeShape(w interface{ Write([]byte) (int, error) }, t reflect.Type) {
+	_, _ = w.Write([]byte(t.Kind().String() + ":" + strconv.FormatUint(uint64(t.Size()), 10) + ";"))
+	switch t.Kind() {
+	case reflect.Array:
+		_, _ = w.Write([]byte(strconv.Itoa(t.Len()) + "["))
+		writeTypeShape(w, t.Elem())
+		_, _ = w.Write([]byte("]"))
+	case reflect.Struct:
+		_, _ = w.Write([]byte("{"))
+		for i := range t.NumField() {
+			f := t.Field(i)
+			_, _ = w.Write([]byte(f.Name + "@" + strconv.FormatUint(uint64(f.Offset), 10) + "="))
+			writeTypeShape(w, f.Type)
+		}
+		_, _ = w.Write([]byte("}"))
+	}
+}
+
+// initHeader fills in the channel header for element type T.
+func (c *Channel[T]) initHeader() {
+	c.header.init(layoutMPMC, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]())
+}
+
+// Attach returns the Channel[T] that an earlier Make initialized in the memory
+// block at ptr, without resetting it.
+//
+// Unlike Make, Attach does not write to the block. It reads the header Make
+// stored there and checks that the block holds a channel with the current
+// layout whose elements have the size, alignment and structural fingerprint of
+// T. This makes it safe to reattach to a block that outlives the Channel
+// pointer, such as one shared with another process or persisted to a file.
+//
+// Parameters:
+//   - ptr: Pointer to a memory block previously initialized by Make[T]
+//
+// Returns:
+//   - A pointer to the channel in the block
+//   - ErrNotChannel, ErrLayoutMismatch or ErrTypeMismatch (wrapped with
+//     details) if the block cannot be used as a Channel[T]
+func Attach[T any](ptr unsafe.Pointer) (*Channel[T], error) {
+	c := (*Channel[T])(ptr)
+	if err := c.header.check(layoutMPMC, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]()); err != nil {
+		return nil, err
+	}
+	if c.cap < 0 {
+		return nil, fmt.Errorf("%w: invalid capacity %d", ErrNotChannel, c.cap)
+	}
+	if c.mask != ringMask(c.cap) {
+		return nil, fmt.Errorf("%w: mask %#x does not match capacity %d", ErrNotChannel, c.mask, c.cap)
+	}
+	if c.stride != compactStride && c.stride != paddedStride {
+		return nil, fmt.Errorf("%w: unknown hot word stride %d", ErrLayoutMismatch, c.stride)
+	}
+	if (c.sums != 0 && c.sums != 1) || (c.words-c.sums != 1 && c.words-c.sums != 2) {
+		return nil, fmt.Errorf("%w: %d bookkeeping words per slot", ErrLayoutMismatch, c.words)
+	}
+	return c, nil
+}
+
+// AttachMPSC is like Attach for a block initialized by MakeMPSC. Since MPSC[T]
+// shares the layout of Channel[T], it also accepts blocks initialized by Make.
+func AttachMPSC[T any](ptr unsafe.Pointer) (*MPSC[T], error) {
+	c, err := Attach[T](ptr)
+	return (*MPSC[T])(c), err
+}
+
+// initHeader fills in the channel header for element type T.
+func (c *SPSC[T]) initHeader() {
+	c.header.init(layoutSPSC, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]())
+}
+
+// AttachSPSC is like Attach for a block initialized by MakeSPSC.
+func AttachSPSC[T any](ptr unsafe.Pointer) (*SPSC[T], error) {
+	c := (*SPSC[T])(ptr)
+	if err := c.header.check(layoutSPSC, unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T)), fingerprintOf[T]()); err != nil {
+		return nil, err
+	}
+	if c.cap < 0 {
+		return nil, fmt.Errorf("%w: invalid capacity %d", ErrNotChannel, c.cap)
+	}
+	if c.mask != ringMask(c.cap) {
+		return nil, fmt.Errorf("%w: mask %#x does not match capacity %d", ErrNotChannel, c.mask, c.cap)
+	}
+	return c, nil
+}
@@ -0,0 +1,117 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestAttach(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int64](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int64](ptr, n)
+	assert.True(ch.Push(1))
+	assert.True(ch.Push(2))
+
+	// Attaching keeps the queued values instead of resetting the ring.
+	again, err := xxchan.Attach[int64](ptr)
+	assert.NoError(err)
+	assert.Equal(2, again.Len())
+	val, ok := again.Pop()
+	assert.True(ok)
+	assert.Equal(int64(1), val)
+	assert.Equal(1, ch.Len())
+
+	mpsc, err := xxchan.AttachMPSC[int64](ptr)
+	assert.NoError(err)
+	assert.Equal(n, mpsc.Cap())
+
+	// A struct with the same shape declared elsewhere is accepted.
+	type point struct{ X, Y int32 }
+	type samePoint struct{ X, Y int32 }
+	pptr := alloc(uint(xxchan.Sizeof[point](n)))
+	t.Cleanup(func() { free(pptr) })
+	xxchan.Make[point](pptr, n)
+	_, err = xxchan.Attach[samePoint](pptr)
+	assert.NoError(err)
+}
+
+func TestAttachMismatch(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	size := xxchan.Sizeof[int64](n)
+	ptr := alloc(uint(size))
+	t.Cleanup(func() { free(ptr) })
+
+	// Uninitialized memory is not a channel.
+	clear(unsafe.Slice((*byte)(ptr), size))
+	_, err := xxchan.Attach[int64](ptr)
+	assert.ErrorIs(err, xxchan.ErrNotChannel)
+
+	xxchan.Make[int64](ptr, n)
+
+	_, err = xxchan.Attach[int32](ptr)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.Attach[float64](ptr)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.Attach[struct{ A, B int32 }](ptr)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.AttachSPSC[int64](ptr)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+
+	// Same size and alignment, different field layout.
+	type ab struct{ A, B int32 }
+	type ba struct{ B, A int32 }
+	sptr := alloc(uint(xxchan.Sizeof[ab](n)))
+	t.Cleanup(func() { free(sptr) })
+	xxchan.Make[ab](sptr, n)
+	_, err = xxchan.Attach[ba](sptr)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+}
+
+func TestAttachSPSC(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, n)
+	assert.True(ch.Push(7))
+
+	again, err := xxchan.AttachSPSC[int](ptr)
+	assert.NoError(err)
+	val, ok := again.Pop()
+	assert.True(ok)
+	assert.Equal(7, val)
+
+	_, err = xxchan.Attach[int](ptr)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+}
@@ -0,0 +1,100 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package xxchan
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	memfdName = "xxchan"
+
+	// memfdSeals fix the size of the file and forbid further seals.
+	memfdSeals = unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW
+)
+
+// NewMemfd creates an anonymous memory file, sizes it for a Channel[T] of
+// capacity n, maps it and initializes the channel in it.
+//
+// Unlike Create, NewMemfd leaves nothing in the file system to name, find or
+// clean up: the memory lives as long as some process keeps the file open or
+// mapped. The file's size is sealed, so no process holding it can shrink it
+// under the mappings of the others. Other processes attach to the channel
+// with FromFD, after receiving the file through exec.Cmd.ExtraFiles or, over a
+// Unix domain socket, with SendFD and ReceiveFD. The file is opened
+// close-on-exec, so it only reaches the children it is handed to explicitly.
+//
+// Parameters:
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy(LockFutexShared), WithOverflow,
+//     WithPadding or WithRecovery; AllowPointers has no effect
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - The memory file, to be handed to other processes and closed once it is
+//     no longer needed; closing it does not affect existing mappings
+//   - An error if the file cannot be created, sealed or mapped,
+//     ErrInvalidCapacity or ErrSizeOverflow (wrapped with details) if n is out
+//     of range, or ErrPointerElement if T contains Go pointers
+//
+// Example:
+//
+//	sh, memfd, err := xxchan.NewMemfd[int64](1024)
+//	cmd := exec.Command("worker")
+//	cmd.ExtraFiles = []*os.File{memfd} // fd 3 in the child
+//	err = cmd.Start()
+//	memfd.Close()
+func NewMemfd[T any](n int, opts ...Option) (*Shared[T], *os.File, error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, nil, err
+	}
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrInvalidCapacity, n)
+	}
+	o := newOptions(opts)
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: capacity %d", ErrSizeOverflow, n)
+	}
+	fd, err := unix.MemfdCreate(memfdName, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("memfd_create", err)
+	}
+	f := os.NewFile(uintptr(fd), "memfd:"+memfdName)
+
+	if err := f.Truncate(int64(size)); err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, memfdSeals); err != nil {
+		_ = f.Close()
+		return nil, nil, os.NewSyscallError("fcntl", err)
+	}
+	data, err := mapFile(fd, size)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return &Shared[T]{Channel: Make[T](unsafe.Pointer(&data[0]), n, opts...), data: data}, f, nil
+}
@@ -0,0 +1,165 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package xxchan_test
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestMemfd(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	sh, memfd, err := xxchan.NewMemfd[int64](n)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+	t.Cleanup(func() { _ = memfd.Close() })
+
+	// The size is sealed, so nobody holding the file can pull the memory
+	// out from under a mapping.
+	assert.ErrorIs(memfd.Truncate(0), syscall.EPERM)
+	assert.ErrorIs(memfd.Truncate(1<<20), syscall.EPERM)
+
+	other, err := xxchan.FromFD[int64](memfd.Fd())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = other.Unmap() })
+	assert.Equal(n, other.Cap())
+
+	assert.True(sh.Push(7))
+	v, ok := other.Pop()
+	assert.True(ok)
+	assert.Equal(int64(7), v)
+
+	_, err = xxchan.FromFD[float64](memfd.Fd())
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.FromFD[*int64](memfd.Fd())
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	_, _, err = xxchan.NewMemfd[int64](0)
+	assert.ErrorIs(err, xxchan.ErrInvalidCapacity)
+	_, _, err = xxchan.NewMemfd[int64](math.MaxInt / 2)
+	assert.ErrorIs(err, xxchan.ErrSizeOverflow)
+
+	// A file that does not hold a channel is rejected by the header check.
+	f, err := os.CreateTemp(t.TempDir(), "xxchan")
+	assert.NoError(err)
+	t.Cleanup(func() { _ = f.Close() })
+	assert.NoError(f.Truncate(int64(xxchan.Sizeof[int64](n))))
+	_, err = xxchan.FromFD[int64](f.Fd())
+	assert.ErrorIs(err, xxchan.ErrNotChannel)
+}
+
+func TestMemfdExtraFiles(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	total := 2000
+	sh, memfd, err := xxchan.NewMemfd[int64](64, xxchan.WithLockStrategy(xxchan.LockFutexShared))
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	cmd := helperProcess(t, "TestMemfdProducerProcess",
+		"XXCHAN_MEMFD_FD=3", "XXCHAN_MEMFD_TOTAL="+strconv.Itoa(total))
+	cmd.ExtraFiles = []*os.File{memfd}
+	assert.NoError(cmd.Start())
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+	assert.NoError(memfd.Close())
+
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		v, err := sh.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			assert.Equal(total, i)
+			break
+		}
+		assert.Equal(int64(i), v)
+	}
+	assert.NoError(cmd.Wait())
+}
+
+func TestMemfdUnixSocket(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	assert.NoError(err)
+	conns := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(f)
+		assert.NoError(err)
+		assert.NoError(f.Close())
+		conns[i] = conn.(*net.UnixConn)
+		t.Cleanup(func() { _ = conns[i].Close() })
+	}
+
+	sh, memfd, err := xxchan.NewMemfd[int64](4)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+	assert.NoError(xxchan.SendFD(conns[0], memfd))
+	assert.NoError(memfd.Close())
+
+	received, err := xxchan.ReceiveFD(conns[1])
+	assert.NoError(err)
+	other, err := xxchan.FromFD[int64](received.Fd())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = other.Unmap() })
+	// The mapping outlives the descriptor it was made from.
+	assert.NoError(received.Close())
+
+	assert.True(other.Push(9))
+	v, ok := sh.Pop()
+	assert.True(ok)
+	assert.Equal(int64(9), v)
+}
+
+// TestMemfdProducerProcess runs in the child process started by
+// TestMemfdExtraFiles, which inherits the memory file as descriptor 3.
+func TestMemfdProducerProcess(t *testing.T) {
+	fd := os.Getenv("XXCHAN_MEMFD_FD")
+	if fd == "" {
+		t.Skip("helper process for TestMemfdExtraFiles")
+	}
+	assert := require.New(t)
+	n, err := strconv.Atoi(fd)
+	assert.NoError(err)
+	total, err := strconv.Atoi(os.Getenv("XXCHAN_MEMFD_TOTAL"))
+	assert.NoError(err)
+
+	sh, err := xxchan.FromFD[int64](uintptr(n))
+	assert.NoError(err)
+	defer sh.Unmap()
+
+	ctx := context.Background()
+	for i := range total {
+		assert.NoError(sh.PushWait(ctx, int64(i)))
+	}
+	assert.NoError(sh.Close())
+}
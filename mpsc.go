@@ -0,0 +1,294 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"sync/atomic"
+	"unsafe"
+)
+
+// MPSC is a multi-producer single-consumer variant of Channel[T].
+//
+// It has the same memory layout and method set as Channel[T], but assumes that
+// at most one goroutine pops at any time. Producers claim slots exactly as they
+// do on Channel[T]; the consumer owns the head position outright, so Pop is
+// wait-free and never needs a compare-and-swap.
+//
+// Push, Len, Cap, Closed and Close may be called from any goroutine.
+//
+// Example usage:
+//
+//	size := xxchan.SizeofMPSC[int](100)
+//	buf := make([]byte, size)
+//	ch := xxchan.MakeMPSC[int](unsafe.Pointer(&buf[0]), 100)
+type MPSC[T any] Channel[T]
+
+// SizeofMPSC calculates the total memory size required for an MPSC[T] with
+// the specified capacity. It is the same as Sizeof[T](n, opts...).
+//
+// Parameters:
+//   - n: The desired capacity of the channel (maximum number of elements)
+//   - opts: The layout options that will be passed to MakeMPSC
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the channel
+func SizeofMPSC[T any](n int, opts ...Option) int {
+	return Sizeof[T](n, opts...)
+}
+
+// MakeMPSC initializes a new MPSC[T] using a pre-allocated memory block of at
+// least SizeofMPSC[T](n, opts...) bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy or WithPadding; overflow
+//     policies do not apply to MPSC[T], whose Push always rejects values
+//     when full
+//
+// Returns:
+//   - A pointer to the initialized MPSC[T]
+//
+// The safety requirements of Make apply unchanged.
+func MakeMPSC[T any](ptr unsafe.Pointer, n int, opts ...Option) *MPSC[T] {
+	return (*MPSC[T])(Make[T](ptr, n, opts...))
+}
+
+// mpmc returns the channel viewed as a Channel[T], whose producer side MPSC
+// shares unchanged.
+func (c *MPSC[T]) mpmc() *Channel[T] {
+	return (*Channel[T])(c)
+}
+
+// watch, unwatch and wait make MPSC[T] a waitable.
+func (c *MPSC[T]) watch() int32 {
+	return c.mpmc().watch()
+}
+
+func (c *MPSC[T]) unwatch() {
+	c.mpmc().unwatch()
+}
+
+func (c *MPSC[T]) wait(ctx context.Context, seq int32) error {
+	return c.mpmc().wait(ctx, seq)
+}
+
+// Push attempts to add a value to the channel without blocking.
+//
+// Returns:
+//   - true if the value was successfully added
+//   - false if the channel is full or closed
+func (c *MPSC[T]) Push(val T) (ok bool) {
+	if c == nil {
+		return
+	}
+	return c.push(val) == nil
+}
+
+// TryPush is like Push but reports why the value could not be added.
+//
+// Returns:
+//   - nil if the value was successfully added
+//   - ErrFull if the channel is full
+//   - ErrClosed if the channel has been closed
+func (c *MPSC[T]) TryPush(val T) error {
+	if c == nil {
+		return ErrFull
+	}
+	return c.push(val)
+}
+
+// push implements TryPush.
+func (c *MPSC[T]) push(val T) error {
+	return c.mpmc().push(val)
+}
+
+// PushN attempts to add the values of src to the channel, in order, with the
+// same semantics as Channel[T].PushN.
+//
+// Returns:
+//   - The number of leading values of src that were added
+func (c *MPSC[T]) PushN(src []T) int {
+	return c.mpmc().PushN(src)
+}
+
+// PushWait adds a value to the channel, waiting for free space if it is full.
+//
+// Returns:
+//   - nil if the value was added
+//   - ErrClosed if the channel is or becomes closed while waiting
+//   - ctx.Err() if ctx was done before space became available
+func (c *MPSC[T]) PushWait(ctx context.Context, val T) error {
+	if c == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := c.push(val); err != ErrFull {
+		return err
+	}
+	return pushWait(ctx, c, val)
+}
+
+// Pop attempts to remove and return a value from the channel without
+// blocking. It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T if empty
+//   - ok: true if a value was successfully removed, false if the channel was empty
+func (c *MPSC[T]) Pop() (v T, ok bool) {
+	if c == nil {
+		return
+	}
+	v, err := c.pop()
+	ok = err == nil
+	return
+}
+
+// TryPop is like Pop but distinguishes a channel that is merely empty from one
+// that has been closed and drained.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrEmpty if the channel is empty but still open,
+//     or ErrClosed if the channel is closed and no values remain
+func (c *MPSC[T]) TryPop() (v T, err error) {
+	if c == nil {
+		err = ErrEmpty
+		return
+	}
+	return c.pop()
+}
+
+// pop implements TryPop. With a single consumer the published slot at head
+// cannot be taken by anyone else, so head is advanced with a plain store.
+// As in Channel[T].pop, head moves before the slot is released, so that a
+// consumer killed in between leaves a stamped slot behind that Recover can
+// release.
+func (c *MPSC[T]) pop() (v T, err error) {
+	ch := c.mpmc()
+	if c.cap == 0 {
+		return ch.pop() // Always empty; Channel[T].pop reports it
+	}
+	seqs := ch.seqs()
+	for {
+		head := atomic.LoadInt64(ch.headp())
+		i := ch.index(head)
+		if seq := atomic.LoadInt64(&seqs[i]); seq != 2*head+1 {
+			if seq == abandoned(head) {
+				ch.skip(head)
+				continue
+			}
+			if tail := atomic.LoadInt64(ch.tailp()); tail&closedBit != 0 && tail&^closedBit == head {
+				err = ErrClosed
+			} else {
+				err = ErrEmpty
+			}
+			count(&ch.counters().empty)
+			return
+		}
+		ch.stamp(i, head, true)
+		atomic.StoreInt64(ch.headp(), head+1)
+		v = ch.buffer()[i]
+		atomic.StoreInt64(&seqs[i], 2*(head+c.cap))
+		ch.events().notify()
+		return
+	}
+}
+
+// PopN attempts to remove values from the channel into dst, in order, copying
+// them out of the ring with at most two copy calls.
+// It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - The number of values written to the front of dst; fewer than len(dst)
+//     if the channel ran empty, and 0 if it was empty
+func (c *MPSC[T]) PopN(dst []T) int {
+	if c == nil || len(dst) == 0 || c.cap == 0 {
+		return 0
+	}
+	ch := c.mpmc()
+	seqs := ch.seqs()
+	for {
+		head := atomic.LoadInt64(ch.headp())
+		k := int64(0)
+		for k < int64(len(dst)) && k < c.cap && atomic.LoadInt64(&seqs[ch.index(head+k)]) == 2*(head+k)+1 {
+			k++
+		}
+		if k == 0 {
+			if atomic.LoadInt64(&seqs[ch.index(head)]) == abandoned(head) {
+				ch.skip(head)
+				continue
+			}
+			count(&ch.counters().empty)
+			return 0
+		}
+		ch.stampN(head, k, true)
+		atomic.StoreInt64(ch.headp(), head+k)
+		copyFromRing(dst[:k], ch.buffer(), int(ch.index(head)))
+		for j := range k {
+			atomic.StoreInt64(&seqs[ch.index(head+j)], 2*(head+j+c.cap))
+		}
+		ch.events().notify()
+		return int(k)
+	}
+}
+
+// PopWait removes and returns a value from the channel, waiting for one to
+// arrive if it is empty. It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrClosed if the channel is closed and drained,
+//     or ctx.Err() if ctx was done before a value arrived
+func (c *MPSC[T]) PopWait(ctx context.Context) (v T, err error) {
+	if c == nil {
+		<-ctx.Done()
+		err = ctx.Err()
+		return
+	}
+	if v, err = c.pop(); err != ErrEmpty {
+		return
+	}
+	return popWait(ctx, c)
+}
+
+// Close marks the channel as closed, with the same semantics as
+// Channel[T].Close.
+//
+// Returns:
+//   - nil if the channel was closed by this call
+//   - ErrClosed if the channel had already been closed
+func (c *MPSC[T]) Close() error {
+	return c.mpmc().Close()
+}
+
+// Closed reports whether Close has been called on the channel.
+func (c *MPSC[T]) Closed() bool {
+	return c.mpmc().Closed()
+}
+
+// Len returns a snapshot of the number of elements stored in the channel.
+func (c *MPSC[T]) Len() int {
+	return c.mpmc().Len()
+}
+
+// Cap returns the maximum capacity of the channel.
+func (c *MPSC[T]) Cap() int {
+	return c.mpmc().Cap()
+}
@@ -0,0 +1,146 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestMPSCFull(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, n)
+	assert.Equal(n, ch.Cap())
+
+	for i := range 3 {
+		assert.True(ch.Push(i))
+		assert.False(ch.Push(-1))
+		assert.Equal(1, ch.Len())
+		val, ok := ch.Pop()
+		assert.True(ok)
+		assert.Equal(i, val)
+		_, ok = ch.Pop()
+		assert.False(ok)
+	}
+
+	assert.NoError(ch.Close())
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrClosed)
+	_, err := ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestMPSCZeroCapacity(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](0)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, 0)
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrFull)
+	assert.Equal(0, ch.PushN([]int{1}))
+	_, err := ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrEmpty)
+	assert.Equal(0, ch.PopN(make([]int, 1)))
+
+	assert.NoError(ch.Close())
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestMPSCConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 16
+	producers := 4
+	perProducer := 10000
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, n)
+	ctx := context.Background()
+
+	wg := &sync.WaitGroup{}
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				if err := ch.PushWait(ctx, p*perProducer+i); err != nil {
+					panic(err)
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		_ = ch.Close()
+	}()
+
+	last := make([]int, producers)
+	for i := range last {
+		last[i] = -1
+	}
+	count := 0
+	for {
+		v, err := ch.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			break
+		}
+		p, i := v/perProducer, v%perProducer
+		assert.Equal(last[p]+1, i)
+		last[p] = i
+		count++
+	}
+	assert.Equal(producers*perProducer, count)
+}
+
+func TestMPSCBatch(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 5
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, n)
+
+	assert.Equal(3, ch.PushN([]int{-1, -2, -3}))
+	assert.Equal(3, ch.PopN(make([]int, 3)))
+
+	assert.Equal(n, ch.PushN([]int{0, 1, 2, 3, 4, 5}))
+	dst := make([]int, 2)
+	assert.Equal(2, ch.PopN(dst))
+	assert.Equal([]int{0, 1}, dst)
+	dst = make([]int, 8)
+	assert.Equal(3, ch.PopN(dst))
+	assert.Equal([]int{2, 3, 4}, dst[:3])
+	assert.Equal(0, ch.PopN(dst))
+}
@@ -0,0 +1,114 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+var (
+	// ErrInvalidCapacity is returned by New when the requested capacity is not
+	// positive.
+	ErrInvalidCapacity = errors.New("xxchan: invalid channel capacity")
+
+	// ErrSizeOverflow is returned by New when the memory required for the
+	// requested capacity does not fit in an int.
+	ErrSizeOverflow = errors.New("xxchan: channel size overflows int")
+
+	// ErrBufferTooSmall is returned by New when the buffer is shorter than
+	// Sizeof reports for the requested capacity.
+	ErrBufferTooSmall = errors.New("xxchan: buffer too small for channel")
+
+	// ErrMisaligned is returned by New when the buffer does not start at an
+	// address suitably aligned for the channel header and its elements.
+	ErrMisaligned = errors.New("xxchan: buffer misaligned for channel")
+)
+
+// sizeofChannel returns the unaligned size of a channel block holding n slots
+// of elemSize bytes after a header of hdrSize bytes, each slot carrying
+// seqSize bytes of bookkeeping. It reports false if n is negative or the size
+// does not fit in an int.
+func sizeofChannel(hdrSize, seqSize, elemSize uintptr, n int) (int, bool) {
+	if n < 0 {
+		return 0, false
+	}
+	slot := int(seqSize + elemSize)
+	if slot > 0 && n > (math.MaxInt-int(hdrSize)-maxAlign)/slot {
+		return 0, false
+	}
+	return int(hdrSize) + n*slot, true
+}
+
+// maxAlign is headroom left by sizeofChannel so that rounding the result up to
+// the element alignment cannot overflow either.
+const maxAlign = 1 << 12
+
+// New initializes a Channel[T] in buf after checking that buf can hold it.
+//
+// New is the checked counterpart of Make: instead of trusting the caller, it
+// verifies the capacity, the size arithmetic, the buffer length and its
+// alignment, and reports the first problem as an error rather than
+// corrupting memory. buf must stay alive, and must not be moved or resized,
+// for as long as the channel is used.
+//
+// Since the garbage collector never scans the contents of a []byte, New also
+// rejects element types that contain Go pointers unless AllowPointers is
+// given.
+//
+// Parameters:
+//   - buf: Memory for the channel, at least Sizeof[T](n, opts...) bytes long
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as AllowPointers, WithOverflow, WithLockStrategy or
+//     WithPadding
+//
+// Returns:
+//   - A pointer to the initialized Channel[T]
+//   - ErrInvalidCapacity, ErrSizeOverflow, ErrBufferTooSmall, ErrMisaligned
+//     or ErrPointerElement (wrapped with details) if the channel cannot be
+//     created in buf
+//
+// Example:
+//
+//	buf := make([]byte, xxchan.Sizeof[int](10))
+//	ch, err := xxchan.New[int](buf, 10)
+func New[T any](buf []byte, n int, opts ...Option) (*Channel[T], error) {
+	o := newOptions(opts)
+	if !o.allowPointers {
+		if err := checkPointerFree[T](); err != nil {
+			return nil, err
+		}
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCapacity, n)
+	}
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		return nil, fmt.Errorf("%w: capacity %d", ErrSizeOverflow, n)
+	}
+	if len(buf) < size {
+		return nil, fmt.Errorf("%w: have %d bytes, need %d", ErrBufferTooSmall, len(buf), size)
+	}
+	ptr := unsafe.Pointer(unsafe.SliceData(buf))
+	if align := unsafe.Alignof(Channel[T]{}); uintptr(ptr)%align != 0 {
+		return nil, fmt.Errorf("%w: address %#x is not a multiple of %d", ErrMisaligned, uintptr(ptr), align)
+	}
+	return Make[T](ptr, n, opts...), nil
+}
@@ -0,0 +1,165 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	buf := make([]byte, xxchan.Sizeof[int64](n))
+
+	ch, err := xxchan.New[int64](buf, n)
+	assert.NoError(err)
+	assert.Equal(n, ch.Cap())
+	assert.True(ch.Push(42))
+	val, ok := ch.Pop()
+	assert.True(ok)
+	assert.Equal(int64(42), val)
+
+	// The block New initializes can be reattached like one from Make.
+	again, err := xxchan.Attach[int64](unsafe.Pointer(&buf[0]))
+	assert.NoError(err)
+	assert.Equal(ch, again)
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	size := xxchan.Sizeof[int64](n)
+	buf := make([]byte, size+8)
+
+	_, err := xxchan.New[int64](buf, 0)
+	assert.ErrorIs(err, xxchan.ErrInvalidCapacity)
+	_, err = xxchan.New[int64](buf, -1)
+	assert.ErrorIs(err, xxchan.ErrInvalidCapacity)
+
+	_, err = xxchan.New[int64](nil, n)
+	assert.ErrorIs(err, xxchan.ErrBufferTooSmall)
+	_, err = xxchan.New[int64](buf[:size-1], n)
+	assert.ErrorIs(err, xxchan.ErrBufferTooSmall)
+
+	// make([]byte) is at least word aligned, so an odd offset never is.
+	_, err = xxchan.New[int64](buf[1:], n)
+	assert.ErrorIs(err, xxchan.ErrMisaligned)
+
+	_, err = xxchan.New[int64](buf, math.MaxInt/2)
+	assert.ErrorIs(err, xxchan.ErrSizeOverflow)
+	_, err = xxchan.New[[1 << 20]byte](buf, math.MaxInt>>16)
+	assert.ErrorIs(err, xxchan.ErrSizeOverflow)
+}
+
+func TestNewAlignment(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// Blocks must be 8-byte aligned on every platform, including 32-bit ones
+	// where int64 itself only needs 4 bytes.
+	n := 5
+	words := make([]uint64, xxchan.Sizeof[int64](n)/8+1)
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), len(words)*8)
+	_, err := xxchan.New[int64](buf[4:], n)
+	assert.ErrorIs(err, xxchan.ErrMisaligned)
+
+	ch, err := xxchan.New[int64](buf, n)
+	assert.NoError(err)
+	assert.True(ch.Push(math.MaxInt64))
+	val, ok := ch.Pop()
+	assert.True(ok)
+	assert.Equal(int64(math.MaxInt64), val)
+
+	// Elements are packed at their own alignment, not that of a pointer, so
+	// every extra slot costs exactly its sequence number and its element. The
+	// block as a whole is rounded up to 8 bytes, so allocators handing out
+	// consecutive blocks keep every one of them aligned.
+	assert.Equal(xxchan.Sizeof[[3]byte](n)+8*(8+3), xxchan.Sizeof[[3]byte](n+8))
+	assert.Equal(xxchan.SizeofSPSC[[3]byte](n)+8*3, xxchan.SizeofSPSC[[3]byte](n+8))
+	for i := 1; i < 16; i++ {
+		assert.Zero(xxchan.Sizeof[[3]byte](i) % 8)
+		assert.Zero(xxchan.SizeofSPSC[[3]byte](i) % 8)
+		assert.Zero(xxchan.SizeofBroadcast[[3]byte](i, 2) % 8)
+	}
+}
+
+func TestSizeofOverflow(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	assert.PanicsWithValue(xxchan.ErrSizeOverflow, func() { xxchan.Sizeof[int64](math.MaxInt / 2) })
+	assert.PanicsWithValue(xxchan.ErrSizeOverflow, func() { xxchan.SizeofSPSC[int64](math.MaxInt / 4) })
+	assert.PanicsWithValue(xxchan.ErrInvalidCapacity, func() { xxchan.Sizeof[int64](-1) })
+}
+
+func TestNewPointerElement(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	type inner struct {
+		ID   int64
+		Name string
+	}
+	type event struct {
+		Seq   uint64
+		Items [4]inner
+	}
+
+	buf := make([]byte, xxchan.Sizeof[event](4))
+	_, err := xxchan.New[event](buf, 4)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	assert.ErrorContains(err, "event.Items[0].Name (string)")
+
+	for _, err := range []error{
+		func() error { _, err := xxchan.New[*int](buf, 4); return err }(),
+		func() error { _, err := xxchan.New[[]byte](buf, 1); return err }(),
+		func() error { _, err := xxchan.New[map[int]int](buf, 4); return err }(),
+		func() error { _, err := xxchan.New[any](buf, 1); return err }(),
+		func() error { _, err := xxchan.New[func()](buf, 4); return err }(),
+		func() error { _, err := xxchan.New[chan int](buf, 4); return err }(),
+	} {
+		assert.ErrorIs(err, xxchan.ErrPointerElement)
+	}
+
+	// Pointer-free structs and arrays are accepted.
+	type point struct {
+		X, Y float64
+		Tag  [8]byte
+	}
+	_, err = xxchan.New[point](make([]byte, xxchan.Sizeof[point](4)), 4)
+	assert.NoError(err)
+
+	// AllowPointers opts out when the values are kept alive elsewhere.
+	keep := []int{1}
+	sbuf := make([]byte, xxchan.Sizeof[*int](4))
+	ch, err := xxchan.New[*int](sbuf, 4, xxchan.AllowPointers())
+	assert.NoError(err)
+	assert.True(ch.Push(&keep[0]))
+	val, ok := ch.Pop()
+	assert.True(ok)
+	assert.Same(&keep[0], val)
+}
@@ -0,0 +1,196 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// waitSpins is the number of busy iterations wait performs before it starts
+// yielding the processor to other goroutines.
+const waitSpins = 64
+
+// notifier lets goroutines wait for a channel's state to change without
+// putting any cost on the fast path while nobody is waiting.
+//
+// A waiter registers with watch, re-checks the channel, and then waits for the
+// sequence word l to move past the value watch returned. Every successful state
+// change calls notify, which only touches l while waiters are registered.
+// Because watch registers before the re-check and notify loads the waiter count
+// after the state change, a waiter either sees the change in its re-check or
+// is woken by it.
+type notifier struct {
+	l        int32 // Sequence word, advanced to wake waiters
+	waiters  int32 // Number of goroutines registered via watch
+	strategy int32 // LockStrategy used to back off and wait
+	_        int32
+}
+
+// init resets the notifier for a newly made channel.
+func (n *notifier) init(strategy LockStrategy) {
+	n.l = 0
+	n.waiters = 0
+	n.strategy = int32(strategy)
+}
+
+// watch registers a waiter and returns the current sequence word.
+func (n *notifier) watch() int32 {
+	atomic.AddInt32(&n.waiters, 1)
+	return atomic.LoadInt32(&n.l)
+}
+
+// unwatch removes a waiter registered with watch.
+func (n *notifier) unwatch() {
+	atomic.AddInt32(&n.waiters, -1)
+}
+
+// notify wakes registered waiters after a state change.
+func (n *notifier) notify() {
+	if atomic.LoadInt32(&n.waiters) != 0 {
+		n.wake()
+	}
+}
+
+// wake unconditionally advances the sequence word, for rare transitions such
+// as Close where skipping the waiter check buys nothing.
+func (n *notifier) wake() {
+	atomic.AddInt32(&n.l, 1)
+	switch LockStrategy(n.strategy) {
+	case LockFutex:
+		futexWake(&n.l, false)
+	case LockFutexShared:
+		futexWake(&n.l, true)
+	}
+}
+
+// wait blocks until the sequence word moves past seq or ctx is done, in the
+// way selected by the notifier's LockStrategy.
+//
+// Waiting never allocates. Every strategy checks ctx between attempts, so a
+// canceled context ends the wait even if the channel never changes.
+func (n *notifier) wait(ctx context.Context, seq int32) error {
+	done := ctx.Done()
+	for i := 0; atomic.LoadInt32(&n.l) == seq; i++ {
+		if !n.step(i) {
+			continue
+		}
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+		// Without a deadline the wait cannot be canceled, so futex sleeps
+		// wake up periodically to check ctx.
+		n.sleep(i, seq, done != nil)
+	}
+	select {
+	case <-done:
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// step performs the busy part of the i-th iteration of a wait and reports
+// whether the iteration goes on to check for cancellation and call sleep.
+func (n *notifier) step(i int) bool {
+	switch LockStrategy(n.strategy) {
+	case LockSpin:
+		procyield(spinCycles)
+		return i%waitSpins == 0
+	case LockYield:
+		runtime.Gosched()
+		return true
+	}
+	return i >= waitSpins
+}
+
+// sleep ends the i-th iteration of a wait for the sequence word to move past
+// seq by giving up the processor, in the way selected by the LockStrategy.
+// With poll set, futex sleeps last at most futexPoll, for waits that must
+// also notice something the kernel does not see.
+func (n *notifier) sleep(i int, seq int32, poll bool) {
+	switch strategy := LockStrategy(n.strategy); strategy {
+	case LockBackoff:
+		time.Sleep(min(minBackoff<<min(i-waitSpins, 16), maxBackoff))
+	case LockFutex, LockFutexShared:
+		timeout := time.Duration(0)
+		if poll {
+			timeout = futexPoll
+		}
+		futexWait(&n.l, seq, timeout, strategy == LockFutexShared)
+	case LockAdaptive:
+		runtime.Gosched()
+	}
+}
+
+// waitable is implemented by every channel flavour, so that the blocking
+// operations are written once on top of their non-blocking ones.
+type waitable interface {
+	watch() int32
+	unwatch()
+	wait(ctx context.Context, seq int32) error
+}
+
+// pushWaitable is a waitable that can be pushed to.
+type pushWaitable[T any] interface {
+	waitable
+	push(val T) error
+}
+
+// popWaitable is a waitable that can be popped from.
+type popWaitable[T any] interface {
+	waitable
+	pop() (T, error)
+}
+
+// pushWait implements PushWait for any channel flavour once a first push
+// attempt found the channel full.
+func pushWait[T any, C pushWaitable[T]](ctx context.Context, c C, val T) error {
+	for {
+		seq := c.watch()
+		err := c.push(val)
+		if err == ErrFull {
+			if err = c.wait(ctx, seq); err == nil {
+				c.unwatch()
+				continue
+			}
+		}
+		c.unwatch()
+		return err
+	}
+}
+
+// popWait implements PopWait for any channel flavour once a first pop attempt
+// found the channel empty.
+func popWait[T any, C popWaitable[T]](ctx context.Context, c C) (v T, err error) {
+	for {
+		seq := c.watch()
+		if v, err = c.pop(); err == ErrEmpty {
+			if err = c.wait(ctx, seq); err == nil {
+				c.unwatch()
+				continue
+			}
+		}
+		c.unwatch()
+		return
+	}
+}
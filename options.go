@@ -0,0 +1,42 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+// Option configures how a channel is created. Options are accepted by New and
+// by the Make functions of the channel flavours; an option that does not apply
+// to a flavour is ignored.
+type Option func(*options)
+
+// options holds the settings collected from a list of Option values.
+type options struct {
+	allowPointers bool
+	overflow      Overflow
+	strategy      LockStrategy
+	padded        bool
+	recovery      bool
+	checksums     bool
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,168 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"unsafe"
+)
+
+// Overflow selects what Push does when the channel is full. It is chosen when
+// the channel is created and stored in the channel's header, so every process
+// sharing the channel applies the same policy.
+type Overflow uint8
+
+const (
+	// OverflowReject makes Push fail on a full channel. It is the default.
+	OverflowReject Overflow = iota
+
+	// OverflowBlock makes Push wait until space is available, as PushWait does
+	// with a context that is never canceled.
+	OverflowBlock
+
+	// OverflowDropNewest discards the value being pushed, keeping the values
+	// already queued.
+	OverflowDropNewest
+
+	// OverflowDropOldest evicts the oldest queued value to make room for the
+	// value being pushed, turning the channel into an overwriting ring such as
+	// a flight recorder.
+	OverflowDropOldest
+
+	// OverflowDropRandom discards either the value being pushed or the oldest
+	// queued value, each with equal probability, spreading losses across old
+	// and new data.
+	OverflowDropRandom
+)
+
+// errDropped is reported internally when the overflow policy discarded the
+// value being pushed.
+var errDropped = errors.New("xxchan: value dropped")
+
+// String returns the name of the policy.
+func (o Overflow) String() string {
+	switch o {
+	case OverflowReject:
+		return "reject"
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropRandom:
+		return "drop-random"
+	}
+	return fmt.Sprintf("Overflow(%d)", uint8(o))
+}
+
+// MakeWithOverflow is like Make but sets the overflow policy applied by Push
+// and PushEvict when the channel is full.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The capacity of the channel (maximum number of elements)
+//   - policy: What Push does when the channel is full
+//
+// Returns:
+//   - A pointer to the initialized Channel[T]
+//
+// The safety requirements of Make apply unchanged.
+func MakeWithOverflow[T any](ptr unsafe.Pointer, n int, policy Overflow) *Channel[T] {
+	return Make[T](ptr, n, WithOverflow(policy))
+}
+
+// WithOverflow sets the overflow policy of a channel created by New.
+func WithOverflow(policy Overflow) Option {
+	return func(o *options) { o.overflow = policy }
+}
+
+// Overflow returns the channel's overflow policy.
+func (c *Channel[T]) Overflow() Overflow {
+	if c == nil {
+		return OverflowReject
+	}
+	return Overflow(c.overflow)
+}
+
+// PushEvict adds a value to the channel, applying the channel's overflow
+// policy if it is full, and reports which value, if any, was lost.
+//
+// Under OverflowDropOldest, if other producers refill the channel while a
+// value is being evicted, PushEvict evicts again and returns only the last
+// evicted value.
+//
+// Parameters:
+//   - val: The value to add to the channel
+//
+// Returns:
+//   - The value that was evicted or discarded, which is val itself when the
+//     policy dropped the new value
+//   - true if a value was evicted or discarded
+//   - ErrFull if the channel is full and the policy is OverflowReject,
+//     ErrClosed if the channel has been closed
+func (c *Channel[T]) PushEvict(val T) (old T, evicted bool, err error) {
+	if c == nil {
+		return old, false, ErrFull
+	}
+	if err = c.push(val); err != ErrFull {
+		return old, false, err
+	}
+	old, evicted, err = c.pushOverflow(val)
+	if err == errDropped {
+		return val, true, nil
+	}
+	return old, evicted, err
+}
+
+// pushOverflow applies the overflow policy after push found the channel full.
+// It returns errDropped if the policy discarded val.
+func (c *Channel[T]) pushOverflow(val T) (old T, evicted bool, err error) {
+	if c.cap == 0 && Overflow(c.overflow) != OverflowBlock && Overflow(c.overflow) != OverflowReject {
+		// A channel without slots holds nothing older to evict, so the value
+		// it is given is the only one it can drop.
+		return old, evicted, errDropped
+	}
+	for {
+		switch Overflow(c.overflow) {
+		case OverflowBlock:
+			return old, evicted, pushWait(context.Background(), c, val)
+		case OverflowDropNewest:
+			return old, evicted, errDropped
+		case OverflowDropRandom:
+			if rand.IntN(2) == 0 {
+				return old, evicted, errDropped
+			}
+			fallthrough
+		case OverflowDropOldest:
+			// Pop as a consumer would; if a consumer made room first there is
+			// nothing to evict.
+			if v, err := c.pop(); err == nil {
+				old, evicted = v, true
+			}
+		default:
+			return old, evicted, ErrFull
+		}
+		if err = c.push(val); err != ErrFull {
+			return old, evicted, err
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestOverflowDrop(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 3
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	assert.Equal(xxchan.OverflowReject, ch.Overflow())
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+	assert.False(ch.Push(4))
+	_, evicted, err := ch.PushEvict(4)
+	assert.ErrorIs(err, xxchan.ErrFull)
+	assert.False(evicted)
+
+	ch = xxchan.MakeWithOverflow[int](ptr, n, xxchan.OverflowDropOldest)
+	assert.Equal(xxchan.OverflowDropOldest, ch.Overflow())
+	for i := range 5 {
+		assert.True(ch.Push(i))
+	}
+	old, evicted, err := ch.PushEvict(5)
+	assert.NoError(err)
+	assert.True(evicted)
+	assert.Equal(2, old)
+	dst := make([]int, n)
+	assert.Equal(3, ch.PopN(dst))
+	assert.Equal([]int{3, 4, 5}, dst)
+
+	// Nothing is evicted while there is room.
+	_, evicted, err = ch.PushEvict(6)
+	assert.NoError(err)
+	assert.False(evicted)
+
+	ch = xxchan.MakeWithOverflow[int](ptr, n, xxchan.OverflowDropNewest)
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+	assert.False(ch.Push(4))
+	old, evicted, err = ch.PushEvict(5)
+	assert.NoError(err)
+	assert.True(evicted)
+	assert.Equal(5, old)
+	assert.Equal(3, ch.PopN(dst))
+	assert.Equal([]int{1, 2, 3}, dst)
+
+	assert.NoError(ch.Close())
+	_, _, err = ch.PushEvict(6)
+	assert.ErrorIs(err, xxchan.ErrClosed)
+
+	// Without slots, every dropping policy drops the value pushed.
+	for _, policy := range []xxchan.Overflow{xxchan.OverflowDropOldest, xxchan.OverflowDropNewest, xxchan.OverflowDropRandom} {
+		ch = xxchan.MakeWithOverflow[int](ptr, 0, policy)
+		assert.False(ch.Push(7))
+		old, evicted, err = ch.PushEvict(7)
+		assert.NoError(err)
+		assert.True(evicted)
+		assert.Equal(7, old)
+	}
+}
+
+func TestOverflowDropRandom(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeWithOverflow[int](ptr, n, xxchan.OverflowDropRandom)
+	assert.Equal(n, ch.PushN([]int{0, 1, 2, 3}))
+
+	// Every push on the full channel loses exactly one value, sometimes the
+	// new one and sometimes the oldest.
+	newest, oldest := 0, 0
+	for i := 4; i < 1000; i++ {
+		old, evicted, err := ch.PushEvict(i)
+		assert.NoError(err)
+		assert.True(evicted)
+		if old == i {
+			newest++
+		} else {
+			oldest++
+		}
+		assert.Equal(n, ch.Len())
+	}
+	assert.Positive(newest)
+	assert.Positive(oldest)
+}
+
+func TestOverflowBlock(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	buf := make([]byte, xxchan.Sizeof[int](1))
+	ch, err := xxchan.New[int](buf, 1, xxchan.WithOverflow(xxchan.OverflowBlock))
+	assert.NoError(err)
+	assert.Equal(xxchan.OverflowBlock, ch.Overflow())
+	assert.True(ch.Push(1))
+
+	done := make(chan bool)
+	go func() { done <- ch.Push(2) }()
+
+	select {
+	case <-done:
+		t.Fatal("Push returned on a full channel")
+	case <-time.After(10 * time.Millisecond):
+	}
+	val, err := ch.PopWait(context.Background())
+	assert.NoError(err)
+	assert.Equal(1, val)
+	assert.True(<-done)
+	val, ok := ch.Pop()
+	assert.True(ok)
+	assert.Equal(2, val)
+
+	// TryPush never blocks, whatever the policy.
+	assert.True(ch.Push(3))
+	assert.ErrorIs(ch.TryPush(4), xxchan.ErrFull)
+}
@@ -0,0 +1,62 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+// Spacing of the hot words of a Channel[T]: the head position written by
+// consumers, the tail position written by producers, and the notifier that
+// PushWait and PopWait sleep on.
+const (
+	// compactStride packs the hot words next to each other; it is the size
+	// of the largest of them, the notifier.
+	compactStride = 16
+
+	// paddedStride gives every hot word a line of its own. It spans two
+	// 64-byte cache lines, since many x86 processors prefetch lines in
+	// adjacent pairs, and matches the 128-byte lines of Apple silicon.
+	paddedStride = 128
+)
+
+// WithPadding selects the padded layout for a Channel[T] or MPSC[T].
+//
+// By default a channel keeps its head and tail positions and its wait word in
+// a few adjacent bytes, so every Push on one core invalidates the cache line
+// that Pops on another core are reading, and vice versa. With WithPadding each
+// of them gets a 128-byte line of its own, which costs 592 extra bytes per
+// channel but removes that false sharing between producers and consumers.
+//
+// The layout is part of the channel's size, so the same option must be passed
+// to Sizeof when allocating the block. Attach recognizes either layout.
+//
+// Example:
+//
+//	size := xxchan.Sizeof[int](1024, xxchan.WithPadding())
+//	buf := make([]byte, size)
+//	ch := xxchan.Make[int](unsafe.Pointer(&buf[0]), 1024, xxchan.WithPadding())
+func WithPadding() Option {
+	return func(o *options) {
+		o.padded = true
+	}
+}
+
+// stride returns the spacing of the hot words selected by the options.
+func (o options) stride() int64 {
+	if o.padded {
+		return paddedStride
+	}
+	return compactStride
+}
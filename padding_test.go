@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestPadding(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	compact := xxchan.Sizeof[int](n)
+	padded := xxchan.Sizeof[int](n, xxchan.WithPadding())
+	// Only the padded layout sets the counters and hot words apart.
+	assert.Equal(592, padded-compact)
+	assert.Equal(padded, xxchan.SizeofMPSC[int](n, xxchan.WithPadding()))
+
+	ptr := alloc(uint(padded))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n, xxchan.WithPadding())
+	for i := range n {
+		assert.True(ch.Push(i))
+	}
+	assert.False(ch.Push(n))
+	assert.Equal(n, ch.Len())
+
+	attached, err := xxchan.Attach[int](ptr)
+	assert.NoError(err)
+	for i := range n {
+		v, ok := attached.Pop()
+		assert.True(ok)
+		assert.Equal(i, v)
+	}
+	stats := ch.Stats()
+	assert.Equal(uint64(n), stats.Pushes)
+	assert.Equal(uint64(n), stats.Pops)
+	assert.Equal(uint64(1), stats.Full)
+
+	assert.NoError(ch.Close())
+	_, err = attached.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestPaddingSPSC(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// The producer's and the consumer's words each get a line of their own,
+	// apart from each other and from the read-mostly fields before them.
+	tail, headCache, head, tailCache := xxchan.SPSCOffsets(&xxchan.SPSC[int]{})
+	assert.Equal(tail+8, headCache)
+	assert.Equal(head+8, tailCache)
+	assert.GreaterOrEqual(head-tail, int64(128))
+	assert.GreaterOrEqual(tail, int64(128))
+	assert.GreaterOrEqual(int64(unsafe.Sizeof(xxchan.SPSC[int]{}))-head, int64(128))
+}
+
+func TestPaddingConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 16
+	ptr := alloc(uint(xxchan.Sizeof[int](n, xxchan.WithPadding())))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n, xxchan.WithPadding())
+	ctx := context.Background()
+	producers, perProducer := 4, 1000
+
+	var wg sync.WaitGroup
+	for range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= perProducer; i++ {
+				assert.NoError(ch.PushWait(ctx, i))
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		_ = ch.Close()
+	}()
+
+	sum := 0
+	for {
+		v, err := ch.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			break
+		}
+		sum += v
+	}
+	assert.Equal(producers*perProducer*(perProducer+1)/2, sum)
+}
+
+func TestPaddingNew(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	words := make([]uint64, xxchan.Sizeof[int](n, xxchan.WithPadding())/8)
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), len(words)*8)
+
+	_, err := xxchan.New[int](buf[:xxchan.Sizeof[int](n)], n, xxchan.WithPadding())
+	assert.ErrorIs(err, xxchan.ErrBufferTooSmall)
+
+	ch, err := xxchan.New[int](buf, n, xxchan.WithPadding())
+	assert.NoError(err)
+	assert.True(ch.Push(1))
+
+	mpsc, err := xxchan.AttachMPSC[int](unsafe.Pointer(&buf[0]))
+	assert.NoError(err)
+	v, ok := mpsc.Pop()
+	assert.True(ok)
+	assert.Equal(1, v)
+}
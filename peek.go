@@ -0,0 +1,222 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"iter"
+	"sync/atomic"
+)
+
+// read returns the value published at position pos, if it is still there.
+//
+// The value is copied between two checks of the slot's sequence number, in the
+// manner of a seqlock: if a consumer took the value or a producer reused the
+// slot meanwhile, the copy may be torn and is discarded.
+func (c *Channel[T]) read(pos int64) (v T, ok bool) {
+	seqs := c.seqs()
+	i := c.index(pos)
+	if atomic.LoadInt64(&seqs[i]) != 2*pos+1 {
+		return
+	}
+	v = c.buffer()[i]
+	if atomic.LoadInt64(&seqs[i]) != 2*pos+1 {
+		var zero T
+		return zero, false
+	}
+	return v, true
+}
+
+// Peek returns the value at the head of the channel without removing it.
+//
+// Returns:
+//   - v: The value that the next Pop would return, or zero value of T if empty
+//   - ok: true if the channel held a value
+func (c *Channel[T]) Peek() (v T, ok bool) {
+	return c.PeekAt(0)
+}
+
+// PeekAt returns the i-th queued value, counting from the head, without
+// removing it.
+//
+// The result is a snapshot: concurrent Pops may remove the value, or shift it
+// to a lower index, as soon as PeekAt returns.
+//
+// Parameters:
+//   - i: Zero-based index from the head of the channel
+//
+// Returns:
+//   - v: The value at index i, or zero value of T if there is none
+//   - ok: true if the channel held at least i+1 values
+func (c *Channel[T]) PeekAt(i int) (v T, ok bool) {
+	if c == nil || i < 0 || int64(i) >= c.cap {
+		return
+	}
+	for {
+		head := atomic.LoadInt64(c.headp())
+		v, ok = c.read(head + int64(i))
+		// Retry if a consumer moved head, as the index then refers to another value.
+		if atomic.LoadInt64(c.headp()) == head {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the queued values and their indexes from the
+// head, without removing them.
+//
+// The iterator walks the values between head and tail as they were when
+// iteration started, oldest first. Each value is read with the same check as
+// PeekAt, so iteration stops early, rather than yield a value that is no
+// longer queued, if consumers catch up with it.
+func (c *Channel[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		if c == nil {
+			return
+		}
+		head := atomic.LoadInt64(c.headp())
+		tail := atomic.LoadInt64(c.tailp()) &^ closedBit
+		for pos := head; pos < tail; pos++ {
+			v, ok := c.read(pos)
+			if !ok || !yield(int(pos-head), v) {
+				return
+			}
+		}
+	}
+}
+
+// Drain returns an iterator that pops values from the channel as it is ranged
+// over. Iteration ends once the channel is empty; it does not wait for more
+// values. Values are consumed one by one, so breaking out of the loop leaves
+// the rest in the channel.
+func (c *Channel[T]) Drain() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if c == nil {
+			return
+		}
+		for {
+			v, err := c.pop()
+			if err != nil || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Peek returns the value at the head of the channel without removing it.
+func (c *MPSC[T]) Peek() (v T, ok bool) {
+	return c.mpmc().PeekAt(0)
+}
+
+// PeekAt returns the i-th queued value, counting from the head, without
+// removing it, with the same semantics as Channel[T].PeekAt.
+func (c *MPSC[T]) PeekAt(i int) (v T, ok bool) {
+	return c.mpmc().PeekAt(i)
+}
+
+// All returns an iterator over the queued values and their indexes from the
+// head, with the same semantics as Channel[T].All.
+func (c *MPSC[T]) All() iter.Seq2[int, T] {
+	return c.mpmc().All()
+}
+
+// Drain returns an iterator that pops values from the channel as it is ranged
+// over, until the channel is empty. It must only be used by the consumer
+// goroutine.
+func (c *MPSC[T]) Drain() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if c == nil {
+			return
+		}
+		for {
+			v, err := c.pop()
+			if err != nil || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// read returns the value at position pos, if it is still queued. The producer
+// only reuses a slot once head has passed it, so the copy is valid if head has
+// not reached pos by the time it is taken.
+func (c *SPSC[T]) read(pos int64) (v T, ok bool) {
+	if pos >= atomic.LoadInt64(&c.tail)&^closedBit {
+		return
+	}
+	v = c.buffer()[c.index(pos)]
+	if atomic.LoadInt64(&c.head) > pos {
+		var zero T
+		return zero, false
+	}
+	return v, true
+}
+
+// Peek returns the value at the head of the channel without removing it.
+func (c *SPSC[T]) Peek() (v T, ok bool) {
+	return c.PeekAt(0)
+}
+
+// PeekAt returns the i-th queued value, counting from the head, without
+// removing it, with the same semantics as Channel[T].PeekAt.
+func (c *SPSC[T]) PeekAt(i int) (v T, ok bool) {
+	if c == nil || i < 0 || int64(i) >= c.cap {
+		return
+	}
+	for {
+		head := atomic.LoadInt64(&c.head)
+		v, ok = c.read(head + int64(i))
+		if atomic.LoadInt64(&c.head) == head {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the queued values and their indexes from the
+// head, with the same semantics as Channel[T].All.
+func (c *SPSC[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		if c == nil {
+			return
+		}
+		head := atomic.LoadInt64(&c.head)
+		tail := atomic.LoadInt64(&c.tail) &^ closedBit
+		for pos := head; pos < tail; pos++ {
+			v, ok := c.read(pos)
+			if !ok || !yield(int(pos-head), v) {
+				return
+			}
+		}
+	}
+}
+
+// Drain returns an iterator that pops values from the channel as it is ranged
+// over, until the channel is empty. It must only be used by the consumer
+// goroutine.
+func (c *SPSC[T]) Drain() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if c == nil {
+			return
+		}
+		for {
+			v, err := c.pop()
+			if err != nil || !yield(v) {
+				return
+			}
+		}
+	}
+}
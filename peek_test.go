@@ -0,0 +1,131 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestChannelPeek(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	_, ok := ch.Peek()
+	assert.False(ok)
+
+	// Wrap the ring so the snapshot spans both ends of the buffer.
+	assert.Equal(3, ch.PushN([]int{0, 0, 0}))
+	assert.Equal(3, ch.PopN(make([]int, 3)))
+	assert.Equal(3, ch.PushN([]int{10, 11, 12}))
+
+	val, ok := ch.Peek()
+	assert.True(ok)
+	assert.Equal(10, val)
+	val, ok = ch.PeekAt(2)
+	assert.True(ok)
+	assert.Equal(12, val)
+	_, ok = ch.PeekAt(3)
+	assert.False(ok)
+	_, ok = ch.PeekAt(-1)
+	assert.False(ok)
+	assert.Equal(3, ch.Len())
+
+	var idx, vals []int
+	for i, v := range ch.All() {
+		idx = append(idx, i)
+		vals = append(vals, v)
+	}
+	assert.Equal([]int{0, 1, 2}, idx)
+	assert.Equal([]int{10, 11, 12}, vals)
+	assert.Equal(3, ch.Len())
+
+	for v := range ch.Drain() {
+		assert.Equal(10, v)
+		break
+	}
+	assert.Equal(2, ch.Len())
+	vals = vals[:0]
+	for v := range ch.Drain() {
+		vals = append(vals, v)
+	}
+	assert.Equal([]int{11, 12}, vals)
+	assert.Equal(0, ch.Len())
+}
+
+func TestSPSCPeek(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 3
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, n)
+	assert.Equal(2, ch.PushN([]int{0, 0}))
+	assert.Equal(2, ch.PopN(make([]int, 2)))
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+
+	val, ok := ch.PeekAt(1)
+	assert.True(ok)
+	assert.Equal(2, val)
+
+	var vals []int
+	for _, v := range ch.All() {
+		vals = append(vals, v)
+	}
+	assert.Equal([]int{1, 2, 3}, vals)
+
+	vals = vals[:0]
+	for v := range ch.Drain() {
+		vals = append(vals, v)
+	}
+	assert.Equal([]int{1, 2, 3}, vals)
+	_, ok = ch.Peek()
+	assert.False(ok)
+}
+
+func TestMPSCPeek(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 3
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, n)
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+
+	val, ok := ch.Peek()
+	assert.True(ok)
+	assert.Equal(1, val)
+
+	var vals []int
+	for v := range ch.Drain() {
+		vals = append(vals, v)
+	}
+	assert.Equal([]int{1, 2, 3}, vals)
+}
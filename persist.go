@@ -0,0 +1,166 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+	"unsafe"
+)
+
+// castagnoli is the CRC-32C table used for slot checksums, which most
+// processors compute in hardware.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// checksum returns the checksum of the value in slot i for position pos. The
+// position is covered too, so a value left over from an earlier lap never
+// passes for the current one.
+func (c *Channel[T]) checksum(i, pos int64) int64 {
+	var p [8]byte
+	binary.LittleEndian.PutUint64(p[:], uint64(pos))
+	b := unsafe.Slice((*byte)(unsafe.Pointer(&c.buffer()[i])), unsafe.Sizeof(*new(T)))
+	return int64(crc32.Update(crc32.Update(0, castagnoli, p[:]), castagnoli, b))
+}
+
+// seal records the checksum of the value just written to slot i for position
+// pos, before it is published. Only persistent channels carry checksums.
+func (c *Channel[T]) seal(i, pos int64) {
+	if c.sums == 0 {
+		return
+	}
+	atomic.StoreInt64(&c.checksums()[i], c.checksum(i, pos))
+}
+
+// sealN seals the k slots from position pos on.
+func (c *Channel[T]) sealN(pos, k int64) {
+	if c.sums == 0 {
+		return
+	}
+	for j := range k {
+		c.seal(c.index(pos+j), pos+j)
+	}
+}
+
+// slotState classifies what a slot of a persistent channel says about one
+// position when the channel is repaired.
+type slotState int
+
+const (
+	slotTorn     slotState = iota // Nothing valid was published at the position
+	slotLive                      // A value with a valid checksum was published at the position
+	slotConsumed                  // The slot has moved past the position, which was consumed
+)
+
+// repair rebuilds a persistent channel from whatever state a crash left in its
+// block, which no other goroutine or process may be using.
+//
+// The pages of a mapped file reach the disk in no particular order, so after a
+// crash of the machine each slot, head and tail may each hold any value they
+// held since the last Sync, and a value may be half written. A position is
+// therefore only trusted if its slot publishes it and the slot's checksum
+// matches. The stored head is used as a lower bound, since head only grows;
+// the stored tail is not used at all, apart from whether the channel is
+// closed.
+//
+// The new head is the first position at or after the stored head whose slot
+// has not moved past it. From there, live values are kept and the tail is
+// placed at the first position whose value is torn or was never published.
+// Values published after a torn one are discarded with it, as are claims of
+// producers and consumers that never finished. A position consumed ahead of
+// an earlier one still live is marked abandoned, so that Pop steps over it,
+// as are slots already abandoned by Recover.
+//
+// Returns:
+//   - The number of positions discarded between the new tail and the stored
+//     one, or marked abandoned
+//   - ErrInconsistent if the stored head is negative
+func (c *Channel[T]) repair() (int, error) {
+	seqs := c.seqs()
+	sums := c.checksums()
+	start := atomic.LoadInt64(c.headp())
+	stored := atomic.LoadInt64(c.tailp())
+	if start < 0 {
+		return 0, fmt.Errorf("%w: head %d", ErrInconsistent, start)
+	}
+
+	// slot reports the position the sequence number of slot i refers to, or
+	// false if it cannot belong to slot i.
+	slot := func(i int64) (pos int64, ok bool) {
+		seq := atomic.LoadInt64(&seqs[i]) &^ abandonedBit
+		return seq >> 1, seq >= 0 && c.index(seq>>1) == i
+	}
+	state := func(pos int64) slotState {
+		i := c.index(pos)
+		switch p, ok := slot(i); {
+		case !ok || p < pos:
+			return slotTorn
+		case p > pos:
+			return slotConsumed
+		}
+		switch seq := atomic.LoadInt64(&seqs[i]); {
+		case seq == abandoned(pos):
+			return slotConsumed
+		case seq == 2*pos+1 && atomic.LoadInt64(&sums[i]) == c.checksum(i, pos):
+			return slotLive
+		}
+		return slotTorn
+	}
+
+	// Each slot first stands for a position not consumed at the earliest
+	// position congruent to it from start on, or at the one it refers to if
+	// that is later.
+	head := int64(-1)
+	for i := range c.cap {
+		first := start + (i-c.index(start)+c.cap)%c.cap
+		if p, ok := slot(i); ok && p > first {
+			first = p
+		}
+		if head < 0 || first < head {
+			head = first
+		}
+	}
+
+	discarded := 0
+	tail := head
+	for tail < head+c.cap {
+		s := state(tail)
+		if s == slotTorn {
+			break
+		}
+		if s == slotConsumed && atomic.SwapInt64(&seqs[c.index(tail)], abandoned(tail)) != abandoned(tail) {
+			discarded++
+		}
+		tail++
+	}
+	if old := stored &^ closedBit; old > tail {
+		discarded += int(old - tail)
+	}
+
+	for pos := tail; pos < head+c.cap; pos++ {
+		i := c.index(pos)
+		atomic.StoreInt64(&seqs[i], 2*pos)
+		atomic.StoreInt64(&sums[i], 0)
+	}
+	clear(c.owners())
+	atomic.StoreInt64(c.headp(), head)
+	atomic.StoreInt64(c.tailp(), tail|stored&closedBit)
+	c.events().init(LockStrategy(c.events().strategy))
+	return discarded, c.check()
+}
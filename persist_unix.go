@@ -0,0 +1,265 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux || darwin || dragonfly || freebsd || openbsd
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrLocked is returned by OpenPersistent when another process has the
+// persistent channel open.
+var ErrLocked = errors.New("xxchan: persistent channel in use")
+
+// Persistent is a Channel[T] kept in a memory-mapped regular file, so that the
+// values queued in it survive the process and, once synced, the machine.
+//
+// Every slot of a persistent channel carries a CRC-32C checksum of its value
+// and position, written before the value is published. After a crash,
+// OpenPersistent uses the checksums and the slot sequence numbers, rather
+// than the order in which the kernel happened to write pages back, to find the
+// values that were completely written; see OpenPersistent.
+//
+// Sync is the durability point: every value pushed before Sync returns
+// survives a crash of the machine, and every value popped before it is not
+// delivered again. Without Sync, values survive the process but a crash of
+// the machine may lose recent pushes or redeliver recent pops. The file is
+// locked while mapped, so only one process uses it at a time; within that
+// process, the channel may be used from any number of goroutines. T must not
+// contain pointers.
+//
+// Example usage:
+//
+//	p, err := xxchan.CreatePersistent[int64]("/var/lib/app/jobs", 1024)
+//	p.Push(42)
+//	err = p.Sync()
+//	err = p.Unmap()
+//
+//	// After a restart
+//	p, err := xxchan.OpenPersistent[int64]("/var/lib/app/jobs")
+//	v, ok := p.Pop()
+type Persistent[T any] struct {
+	*Channel[T]
+	data []byte
+	file *os.File
+}
+
+// CreatePersistent creates a file at path, sizes it for a persistent
+// Channel[T] of capacity n, maps it into memory, initializes the channel in it
+// and syncs it to disk. CreatePersistent fails if the file already exists.
+//
+// Parameters:
+//   - path: Path of the file to create, on a disk-backed file system
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy, WithOverflow or WithPadding;
+//     AllowPointers has no effect
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - An error if the file cannot be created, locked or mapped,
+//     ErrInvalidCapacity or ErrSizeOverflow (wrapped with details) if n is out
+//     of range, or ErrPointerElement if T contains Go pointers
+func CreatePersistent[T any](path string, n int, opts ...Option) (*Persistent[T], error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCapacity, n)
+	}
+	opts = append(opts[:len(opts):len(opts)], func(o *options) { o.checksums = true })
+	o := newOptions(opts)
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		return nil, fmt.Errorf("%w: capacity %d", ErrSizeOverflow, n)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	p, err := func() (*Persistent[T], error) {
+		if err := lockFile(f); err != nil {
+			return nil, err
+		}
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+		data, err := mapFile(int(f.Fd()), size)
+		if err != nil {
+			return nil, err
+		}
+		p := &Persistent[T]{Channel: Make[T](unsafe.Pointer(&data[0]), n, opts...), data: data, file: f}
+		if err := p.Sync(); err != nil {
+			_ = syscall.Munmap(data)
+			return nil, err
+		}
+		return p, syncDir(path)
+	}()
+	if err != nil {
+		_ = os.Remove(path)
+		_ = f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// OpenPersistent maps a persistent channel file created by CreatePersistent
+// and repairs whatever state a crash left in it, then syncs the repaired
+// channel to disk before returning it.
+//
+// The header is checked as by Attach. A file cut short, as by a crash while
+// the file system extended it, is extended again, and the values it lost are
+// treated as torn. The channel's head is taken from the file, skipping
+// values whose slots show them consumed. From there, every value whose slot
+// publishes it with a matching checksum is kept, up to the first one that is
+// missing or torn, where the tail is placed: the value a producer was writing
+// when the process or machine died, and any published after it, are
+// discarded, and nothing half written is ever popped. Slots claimed by a
+// consumer that never finished are released, so their values are lost.
+//
+// Parameters:
+//   - path: Path of the file passed to CreatePersistent
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - An error if the file cannot be opened or mapped, ErrLocked if another
+//     process has it open, ErrNotChannel, ErrLayoutMismatch or
+//     ErrTypeMismatch if it does not hold a persistent Channel[T],
+//     ErrInconsistent if it is damaged beyond repair, or ErrPointerElement if
+//     T contains Go pointers
+func OpenPersistent[T any](path string) (*Persistent[T], error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	p, err := openPersistent[T](f, path)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// openPersistent implements OpenPersistent for the file f.
+func openPersistent[T any](f *os.File, path string) (*Persistent[T], error) {
+	if err := lockFile(f); err != nil {
+		return nil, err
+	}
+	data, err := mapWhole(int(f.Fd()), path, int(unsafe.Sizeof(Channel[T]{})))
+	if err != nil {
+		return nil, err
+	}
+	c, err := Attach[T](unsafe.Pointer(&data[0]))
+	if err == nil && c.sums == 0 {
+		err = fmt.Errorf("%w: %s is not a persistent channel", ErrLayoutMismatch, path)
+	}
+	var size int
+	if err == nil {
+		size, _ = sizeofMPMC[T](int(c.cap), c.stride, c.words)
+		if len(data) > size {
+			err = fmt.Errorf("%w: %s is %d bytes, want %d", ErrLayoutMismatch, path, len(data), size)
+		}
+	}
+	if err == nil && len(data) < size {
+		// The lost tail of the file reads as zeros, which no checksum of a
+		// published value matches.
+		_ = syscall.Munmap(data)
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+		if data, err = mapFile(int(f.Fd()), size); err != nil {
+			return nil, err
+		}
+		c = (*Channel[T])(unsafe.Pointer(&data[0]))
+	}
+	if err == nil {
+		_, err = c.repair()
+	}
+	if err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	p := &Persistent[T]{Channel: c, data: data, file: f}
+	if err := p.Sync(); err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	return p, nil
+}
+
+// lockFile takes the exclusive lock that keeps a second process from mapping
+// a persistent channel. The lock goes away with the file descriptor, also when
+// the process dies.
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return fmt.Errorf("%w: %s", ErrLocked, f.Name())
+		}
+		return &os.PathError{Op: "flock", Path: f.Name(), Err: err}
+	}
+	return nil
+}
+
+// syncDir syncs the directory holding path, so that a newly created file is
+// still found after a crash.
+func syncDir(path string) error {
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Sync writes the channel to disk and waits until the disk has it. The mapping
+// is flushed with msync before the file is synced with fsync, so that the
+// pages written through the mapping are part of what fsync makes durable.
+func (p *Persistent[T]) Sync() error {
+	if p == nil || p.data == nil {
+		return nil
+	}
+	if err := unix.Msync(p.data, unix.MS_SYNC); err != nil {
+		return &os.PathError{Op: "msync", Path: p.file.Name(), Err: err}
+	}
+	return p.file.Sync()
+}
+
+// Unmap syncs the channel to disk, releases the mapping and closes the file.
+// The channel must not be used afterwards; reopen the file with
+// OpenPersistent.
+func (p *Persistent[T]) Unmap() error {
+	if p == nil || p.data == nil {
+		return nil
+	}
+	err := p.Sync()
+	data := p.data
+	p.data = nil
+	p.Channel = nil
+	return errors.Join(err, syscall.Munmap(data), p.file.Close())
+}
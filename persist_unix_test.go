@@ -0,0 +1,368 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux || darwin || dragonfly || freebsd || openbsd
+
+package xxchan_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+// persistentPath returns a fresh path for a persistent channel file on a
+// disk-backed file system.
+func persistentPath(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "chan")
+}
+
+// drain pops every value left in c.
+func drain(c *xxchan.Channel[int64]) []int64 {
+	var vals []int64
+	for {
+		v, ok := c.Pop()
+		if !ok {
+			return vals
+		}
+		vals = append(vals, v)
+	}
+}
+
+// writeAt overwrites the file at path at offset off, as a crash or a faulty
+// disk might.
+func writeAt(t *testing.T, path string, off int64, b []byte) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteAt(b, off)
+	require.NoError(t, err)
+}
+
+// putInt64 overwrites the 64-bit word at offset off of the file at path.
+func putInt64(t *testing.T, path string, off, v int64) {
+	var b [8]byte
+	binary.NativeEndian.PutUint64(b[:], uint64(v))
+	writeAt(t, path, off, b[:])
+}
+
+// createFilled creates a persistent channel of capacity n at path, pushes the
+// values 0 to k-1 and pops the first popped of them, popping early whenever
+// the channel is full. It returns the offsets
+// of the channel's head and of the slot of every position up to n+k.
+func createFilled(t *testing.T, path string, n, k, popped int) (head int64, seq, sum, val func(pos int64) int64) {
+	assert := require.New(t)
+	p, err := xxchan.CreatePersistent[int64](path, n)
+	assert.NoError(err)
+	pop := func() {
+		_, ok := p.Pop()
+		assert.True(ok)
+		popped--
+	}
+	for i := range k {
+		if !p.Push(int64(i)) {
+			pop()
+			assert.True(p.Push(int64(i)))
+		}
+	}
+	for popped > 0 {
+		pop()
+	}
+	head, _, _, _ = xxchan.SlotOffsets(p.Channel, 0)
+	offset := func(field int) func(pos int64) int64 {
+		offs := make([][4]int64, n+k)
+		for pos := range offs {
+			h, s, c, v := xxchan.SlotOffsets(p.Channel, int64(pos))
+			offs[pos] = [4]int64{h, s, c, v}
+		}
+		return func(pos int64) int64 { return offs[pos][field] }
+	}
+	seq, sum, val = offset(1), offset(2), offset(3)
+	assert.NoError(p.Unmap())
+	return
+}
+
+func TestPersistent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := persistentPath(t)
+	p, err := xxchan.CreatePersistent[int64](path, 8)
+	assert.NoError(err)
+	for i := range 5 {
+		assert.True(p.Push(int64(i)))
+	}
+	v, ok := p.Pop()
+	assert.True(ok)
+	assert.Equal(int64(0), v)
+	assert.NoError(p.Sync())
+
+	_, err = xxchan.CreatePersistent[int64](path, 8)
+	assert.ErrorIs(err, os.ErrExist)
+	_, err = xxchan.OpenPersistent[int64](path)
+	assert.ErrorIs(err, xxchan.ErrLocked)
+	assert.NoError(p.Unmap())
+	assert.NoError(p.Unmap())
+
+	p, err = xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	assert.Equal(8, p.Cap())
+	assert.Equal([]int64{1, 2, 3, 4}, drain(p.Channel))
+	for i := range 8 {
+		assert.True(p.Push(int64(10 + i)))
+	}
+	assert.NoError(p.Close())
+	assert.NoError(p.Unmap())
+
+	// Going around the ring and closing survive reopening as well.
+	p, err = xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.True(p.Closed())
+	assert.Equal([]int64{10, 11, 12, 13, 14, 15, 16, 17}, drain(p.Channel))
+	_, err = p.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestPersistentErrors(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := persistentPath(t)
+	p, err := xxchan.CreatePersistent[int64](path, 8)
+	assert.NoError(err)
+	assert.NoError(p.Unmap())
+
+	_, err = xxchan.OpenPersistent[float64](path)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.OpenPersistent[*int64](path)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	_, err = xxchan.CreatePersistent[string](path+"-string", 8)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	_, err = xxchan.CreatePersistent[int64](path+"-empty", 0)
+	assert.ErrorIs(err, xxchan.ErrInvalidCapacity)
+	_, err = xxchan.CreatePersistent[int64](path+"-huge", math.MaxInt/2)
+	assert.ErrorIs(err, xxchan.ErrSizeOverflow)
+	_, err = os.Stat(path + "-huge")
+	assert.ErrorIs(err, os.ErrNotExist)
+	_, err = xxchan.OpenPersistent[int64](path + "-missing")
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// A channel without checksums cannot be repaired.
+	sh, err := xxchan.Create[int64](path+"-shared", 8)
+	assert.NoError(err)
+	assert.NoError(sh.Unmap())
+	_, err = xxchan.OpenPersistent[int64](path + "-shared")
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+
+	// Neither can one whose header is damaged.
+	writeAt(t, path, 0, []byte("junk"))
+	_, err = xxchan.OpenPersistent[int64](path)
+	assert.ErrorIs(err, xxchan.ErrNotChannel)
+	assert.NoError(os.Truncate(path, 10))
+	_, err = xxchan.OpenPersistent[int64](path)
+	assert.ErrorIs(err, xxchan.ErrNotChannel)
+}
+
+func TestPersistentCorruptValue(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := persistentPath(t)
+	_, _, _, val := createFilled(t, path, 16, 8, 0)
+
+	// A value that never fully reached the disk is torn, and so are the
+	// values published after it.
+	writeAt(t, path, val(5), []byte{0xff})
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal(5, p.Len())
+	assert.Equal([]int64{0, 1, 2, 3, 4}, drain(p.Channel))
+
+	// The repaired channel carries on from the torn position.
+	assert.True(p.Push(100))
+	assert.Equal([]int64{100}, drain(p.Channel))
+}
+
+func TestPersistentCorruptChecksum(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := persistentPath(t)
+	_, _, sum, _ := createFilled(t, path, 16, 8, 2)
+	putInt64(t, path, sum(3), 0)
+
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal([]int64{2}, drain(p.Channel))
+}
+
+func TestPersistentUnpublished(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// A producer that dies between claiming a slot and publishing it leaves
+	// the tail past a slot that will never be filled.
+	path := persistentPath(t)
+	p, err := xxchan.CreatePersistent[int64](path, 8)
+	assert.NoError(err)
+	assert.True(p.Push(0))
+	assert.True(p.Push(1))
+	assert.True(xxchan.ClaimPush(p.Channel))
+	assert.True(p.Push(3))
+	assert.NoError(p.Unmap())
+
+	p, err = xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal(2, p.Len())
+	assert.Equal([]int64{0, 1}, drain(p.Channel))
+	for i := range 8 {
+		assert.True(p.Push(int64(10 + i)))
+	}
+	assert.False(p.Push(18))
+}
+
+func TestPersistentStaleHead(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// The head written back to disk lags behind the slots that show their
+	// values consumed.
+	path := persistentPath(t)
+	head, _, _, _ := createFilled(t, path, 8, 6, 3)
+	putInt64(t, path, head, 0)
+
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	assert.Equal([]int64{3, 4, 5}, drain(p.Channel))
+	assert.NoError(p.Unmap())
+
+	// Here a consumed slot did not reach the disk, but a later one did. The
+	// stale value is delivered again, and the consumed one stepped over.
+	path = persistentPath(t) + "-2"
+	head, seq, _, _ := createFilled(t, path, 8, 6, 3)
+	putInt64(t, path, head, 0)
+	putInt64(t, path, seq(1), 2*1+1)
+
+	p, err = xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal([]int64{1, 3, 4, 5}, drain(p.Channel))
+}
+
+func TestPersistentWrapped(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// The stored head is several laps behind, and the live values span the
+	// end of the ring.
+	path := persistentPath(t)
+	head, _, _, _ := createFilled(t, path, 4, 14, 11)
+	putInt64(t, path, head, 0)
+
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal([]int64{11, 12, 13}, drain(p.Channel))
+}
+
+func TestPersistentTruncated(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// The file lost the last two values of the ring.
+	path := persistentPath(t)
+	_, _, _, val := createFilled(t, path, 8, 8, 0)
+	assert.NoError(os.Truncate(path, val(6)))
+
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	assert.Equal([]int64{0, 1, 2, 3, 4, 5}, drain(p.Channel))
+	info, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(val(7)+8, info.Size())
+}
+
+func TestPersistentKilled(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := persistentPath(t)
+	p, err := xxchan.CreatePersistent[int64](path, 64)
+	assert.NoError(err)
+	assert.NoError(p.Unmap())
+
+	cmd := helperProcess(t, "TestPersistentProcess", "XXCHAN_PERSISTENT_PATH="+path)
+	cmd.Stdout = nil
+	out, err := cmd.StdoutPipe()
+	assert.NoError(err)
+	assert.NoError(cmd.Start())
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	// Kill the child while it pushes and pops as fast as it can.
+	line, err := bufio.NewReader(out).ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("ready\n", line)
+	assert.NoError(cmd.Process.Signal(syscall.SIGKILL))
+	_ = cmd.Wait()
+
+	p, err = xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = p.Unmap() })
+	vals := drain(p.Channel)
+	assert.NotEmpty(vals)
+	assert.LessOrEqual(len(vals), 64)
+	for i := 1; i < len(vals); i++ {
+		assert.Equal(vals[i-1]+1, vals[i], "values %v", vals)
+	}
+}
+
+// TestPersistentProcess runs in the child process started by
+// TestPersistentKilled, and keeps pushing until it is killed.
+func TestPersistentProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_PERSISTENT_PATH")
+	if path == "" {
+		t.Skip("helper process for TestPersistentKilled")
+	}
+	assert := require.New(t)
+	p, err := xxchan.OpenPersistent[int64](path)
+	assert.NoError(err)
+
+	for i := int64(0); ; i++ {
+		for !p.Push(i) {
+			p.Pop()
+		}
+		if i%1000 == 0 {
+			assert.NoError(p.Sync())
+		}
+		if i == 10000 {
+			_, err := os.Stdout.WriteString("ready\n")
+			assert.NoError(err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// ErrInconsistent is returned by Recover when the head and tail positions or
+// the slot sequence numbers describe a state the channel can never be in, as
+// when the block has been overwritten.
+var ErrInconsistent = errors.New("xxchan: channel state inconsistent")
+
+// abandonedBit marks the sequence number of a slot whose producer died after
+// claiming it and before publishing a value. Consumers step over such slots.
+const abandonedBit = int64(1) << 62
+
+// selfPID is the process ID recorded in the owner stamps this process writes.
+var selfPID = int64(os.Getpid())
+
+// WithRecovery makes a Channel[T] or MPSC[T] record which process owns each
+// slot while a value is being written to or read from it, so that Recover can
+// repair the channel after a process sharing it was killed halfway through a
+// Push or a Pop.
+//
+// The channel stays lock-free, so a dead process never blocks the others
+// outright. It can, however, leave a slot it had claimed behind: a producer
+// that dies before publishing its value stops consumers at that slot, and a
+// consumer that dies before releasing its slot stops producers there one lap
+// later. Owner stamps cost one 8-byte word per slot and one extra store per
+// Push and Pop. The same option must be passed to Sizeof.
+func WithRecovery() Option {
+	return func(o *options) { o.recovery = true }
+}
+
+// slotWords returns the number of 8-byte bookkeeping words per slot selected
+// by the options.
+func (o options) slotWords() int64 {
+	words := int64(1)
+	if o.recovery {
+		words++
+	}
+	if o.checksums {
+		words++
+	}
+	return words
+}
+
+// abandoned returns the sequence number Recover gives the slot of position pos
+// when its producer died.
+func abandoned(pos int64) int64 {
+	return (2*pos + 1) | abandonedBit
+}
+
+// stamp records this process as the owner of slot i while it writes or reads
+// position pos. The stamp holds the process ID in its upper half and, as a
+// generation, the low bits of pos together with whether the owner is a
+// consumer, so a stamp left over from an earlier lap or from the other side is
+// never mistaken for the current claim.
+func (c *Channel[T]) stamp(i, pos int64, consumer bool) {
+	if c.words-c.sums < 2 {
+		return
+	}
+	atomic.StoreInt64(&c.owners()[i], ownerStamp(selfPID, pos, consumer))
+}
+
+// stampN stamps the k slots from position pos on.
+func (c *Channel[T]) stampN(pos, k int64, consumer bool) {
+	if c.words-c.sums < 2 {
+		return
+	}
+	for j := range k {
+		c.stamp(c.index(pos+j), pos+j, consumer)
+	}
+}
+
+// ownerStamp returns the owner stamp for process pid claiming position pos.
+func ownerStamp(pid, pos int64, consumer bool) int64 {
+	gen := uint32(pos) << 1
+	if consumer {
+		gen |= 1
+	}
+	return pid<<32 | int64(gen)
+}
+
+// skip steps head over the abandoned slot at position head, releasing the
+// slot for the next lap.
+func (c *Channel[T]) skip(head int64) {
+	if atomic.CompareAndSwapInt64(c.headp(), head, head+1) {
+		atomic.StoreInt64(&c.seqs()[c.index(head)], 2*(head+c.cap))
+		c.events().notify()
+	}
+}
+
+// Recover repairs the channel after a process using it died while it owned a
+// slot, and then checks that the channel's positions are consistent.
+//
+// For every slot claimed but not yet published by a producer whose process no
+// longer exists, Recover marks the slot abandoned, and consumers step over it;
+// the value being pushed is lost. For every slot claimed but not yet released
+// by a consumer whose process no longer exists, Recover releases the slot to
+// producers; the value being popped is lost. Recover can be called at any time
+// from any process, concurrently with other operations: a slot whose owner is
+// alive, or whose owner is unknown because it died in the few instructions
+// between claiming and stamping the slot, is left alone.
+//
+// Owners are only recorded by channels made WithRecovery. For other channels
+// Recover just runs the consistency check.
+//
+// Returns:
+//   - The number of slots repaired
+//   - ErrInconsistent (wrapped with details) if the head and tail positions
+//     or the slot sequence numbers are impossible
+func (c *Channel[T]) Recover() (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+	repaired := 0
+	if owners := c.owners(); owners != nil {
+		seqs := c.seqs()
+		head := atomic.LoadInt64(c.headp())
+		tail := atomic.LoadInt64(c.tailp()) &^ closedBit
+		for pos := head; pos < tail; pos++ {
+			i := c.index(pos)
+			if atomic.LoadInt64(&seqs[i]) == 2*pos && ownerDead(atomic.LoadInt64(&owners[i]), pos, false) &&
+				atomic.CompareAndSwapInt64(&seqs[i], 2*pos, abandoned(pos)) {
+				repaired++
+			}
+		}
+		for pos := max(head-c.cap, 0); pos < head; pos++ {
+			i := c.index(pos)
+			if atomic.LoadInt64(&seqs[i]) == 2*pos+1 && ownerDead(atomic.LoadInt64(&owners[i]), pos, true) &&
+				atomic.CompareAndSwapInt64(&seqs[i], 2*pos+1, 2*(pos+c.cap)) {
+				repaired++
+			}
+		}
+		if repaired > 0 {
+			c.events().wake()
+		}
+	}
+	return repaired, c.check()
+}
+
+// ownerDead reports whether stamp records a claim of position pos, by a
+// consumer or a producer, whose process has exited.
+func ownerDead(stamp, pos int64, consumer bool) bool {
+	if uint32(stamp) != uint32(ownerStamp(0, pos, consumer)) {
+		return false
+	}
+	pid := stamp >> 32
+	return pid != selfPID && !processAlive(int(pid))
+}
+
+// check verifies the invariants that hold in every reachable state of the
+// channel, whatever other goroutines and processes do meanwhile: tail never
+// falls behind head nor runs more than a lap ahead of it, and the sequence
+// number of every claimed slot has reached its position.
+func (c *Channel[T]) check() error {
+	head := atomic.LoadInt64(c.headp())
+	tail := atomic.LoadInt64(c.tailp()) &^ closedBit
+	// head only grows, so reading it again after tail bounds the lap from
+	// above without a false alarm.
+	if head < 0 || tail < head || tail-atomic.LoadInt64(c.headp()) > c.cap {
+		return fmt.Errorf("%w: head %d, tail %d, capacity %d", ErrInconsistent, head, tail, c.cap)
+	}
+	seqs := c.seqs()
+	for pos := max(head, tail-c.cap); pos < tail; pos++ {
+		if seq := atomic.LoadInt64(&seqs[c.index(pos)]) &^ abandonedBit; seq < 2*pos {
+			return fmt.Errorf("%w: slot %d has sequence %d below position %d", ErrInconsistent, c.index(pos), seq, pos)
+		}
+	}
+	return nil
+}
+
+// Recover repairs the channel after a process using it died while it owned a
+// slot; see Channel.Recover.
+func (c *MPSC[T]) Recover() (int, error) {
+	return c.mpmc().Recover()
+}
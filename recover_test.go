@@ -0,0 +1,242 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build unix
+
+package xxchan_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+// killOwner starts a child process that opens the channel at path and claims
+// a slot as role, waits until claimed reports the claim, and kills the child
+// with SIGKILL while it owns the slot.
+func killOwner(t *testing.T, path, role string, claimed func() bool) {
+	cmd := helperProcess(t, "TestRecoverOwnerProcess", "XXCHAN_RECOVER_PATH="+path, "XXCHAN_RECOVER_ROLE="+role)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	deadline := time.Now().Add(time.Minute)
+	for !claimed() {
+		require.True(t, time.Now().Before(deadline), "%s did not claim a slot", role)
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, cmd.Process.Signal(syscall.SIGKILL))
+	_ = cmd.Wait()
+}
+
+func TestRecoverDeadProducer(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	sh, err := xxchan.Create[int64](path, 4, xxchan.WithRecovery())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	assert.True(sh.Push(1))
+	killOwner(t, path, "producer", func() bool { return sh.Stats().Pushes == 2 })
+	assert.True(sh.Push(3))
+
+	// The dead producer's slot stops consumers until it is recovered.
+	v, ok := sh.Pop()
+	assert.True(ok)
+	assert.Equal(int64(1), v)
+	_, ok = sh.Pop()
+	assert.False(ok)
+
+	repaired, err := sh.Recover()
+	assert.NoError(err)
+	assert.Equal(1, repaired)
+
+	v, ok = sh.Pop()
+	assert.True(ok)
+	assert.Equal(int64(3), v)
+	assert.Equal(0, sh.Len())
+
+	repaired, err = sh.Recover()
+	assert.NoError(err)
+	assert.Equal(0, repaired)
+}
+
+func TestRecoverDeadConsumer(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	n := 4
+	sh, err := xxchan.Create[int64](path, n, xxchan.WithRecovery())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	for i := range n {
+		assert.True(sh.Push(int64(i)))
+	}
+	killOwner(t, path, "consumer", func() bool { return sh.Stats().Pops == 1 })
+	for i := 1; i < n; i++ {
+		v, ok := sh.Pop()
+		assert.True(ok)
+		assert.Equal(int64(i), v)
+	}
+
+	// The dead consumer's slot stops producers one lap later, although the
+	// channel is empty.
+	assert.Equal(0, sh.Len())
+	assert.False(sh.Push(10))
+
+	repaired, err := sh.Recover()
+	assert.NoError(err)
+	assert.Equal(1, repaired)
+
+	for i := range n {
+		assert.True(sh.Push(int64(10 + i)))
+	}
+	for i := range n {
+		v, ok := sh.Pop()
+		assert.True(ok)
+		assert.Equal(int64(10+i), v)
+	}
+}
+
+func TestRecoverAbandonedWrap(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	sh, err := xxchan.Create[int64](path, 2, xxchan.WithRecovery())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	killOwner(t, path, "producer", func() bool { return sh.Stats().Pushes == 1 })
+	repaired, err := sh.Recover()
+	assert.NoError(err)
+	assert.Equal(1, repaired)
+	assert.True(sh.Push(1))
+
+	// The ring wraps onto the abandoned slot before any consumer has stepped
+	// over it, so it is still full rather than contended.
+	assert.ErrorIs(sh.TryPush(2), xxchan.ErrFull)
+	assert.False(sh.Push(2))
+	assert.Equal(0, sh.PushN([]int64{2, 3}))
+	val := int64(2)
+	cases := []xxchan.Case{xxchan.PushCase(sh.Channel, &val), xxchan.DefaultCase()}
+	chosen, err := xxchan.Select(context.Background(), cases)
+	assert.NoError(err)
+	assert.Equal(1, chosen)
+
+	v, ok := sh.Pop()
+	assert.True(ok)
+	assert.Equal(int64(1), v)
+	assert.True(sh.Push(2))
+	assert.True(sh.Push(3))
+	assert.Equal(2, sh.Len())
+}
+
+func TestRecoverDeadConsumerMPSC(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	n := 4
+	sh, err := xxchan.Create[int64](path, n, xxchan.WithRecovery())
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+	ch := (*xxchan.MPSC[int64])(sh.Channel)
+
+	for i := range n {
+		assert.True(ch.Push(int64(i)))
+	}
+	killOwner(t, path, "mpsc-consumer", func() bool { return ch.Stats().Pops == 1 })
+	dst := make([]int64, n)
+	assert.Equal(n-1, ch.PopN(dst))
+	assert.Equal([]int64{1, 2, 3}, dst[:n-1])
+
+	// The dead consumer's slot stops producers one lap later.
+	assert.False(ch.Push(10))
+	repaired, err := ch.Recover()
+	assert.NoError(err)
+	assert.Equal(1, repaired)
+
+	for i := range n {
+		assert.True(ch.Push(int64(10 + i)))
+	}
+	for i := range n {
+		v, ok := ch.Pop()
+		assert.True(ok)
+		assert.Equal(int64(10+i), v)
+	}
+}
+
+func TestRecoverLiveOwner(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int64](n, xxchan.WithRecovery())))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int64](ptr, n, xxchan.WithRecovery())
+	assert.True(xxchan.ClaimPush(ch))
+	assert.True(ch.Push(2))
+
+	// The owner of the claimed slot, this process, is alive, so the claim may
+	// still be published and Recover leaves it alone.
+	repaired, err := ch.Recover()
+	assert.NoError(err)
+	assert.Equal(0, repaired)
+	_, ok := ch.Pop()
+	assert.False(ok)
+
+	// Without owner stamps Recover only checks consistency.
+	pptr := alloc(uint(xxchan.Sizeof[int64](n)))
+	t.Cleanup(func() { free(pptr) })
+	plain := xxchan.Make[int64](pptr, n)
+	assert.True(plain.Push(1))
+	repaired, err = plain.Recover()
+	assert.NoError(err)
+	assert.Equal(0, repaired)
+}
+
+// TestRecoverOwnerProcess runs in the child processes started by killOwner.
+// It claims a slot and then waits to be killed.
+func TestRecoverOwnerProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_RECOVER_PATH")
+	if path == "" {
+		t.Skip("helper process for the TestRecoverDead tests")
+	}
+	assert := require.New(t)
+
+	sh, err := xxchan.Open[int64](path)
+	assert.NoError(err)
+	switch os.Getenv("XXCHAN_RECOVER_ROLE") {
+	case "producer":
+		assert.True(xxchan.ClaimPush(sh.Channel))
+	case "mpsc-consumer":
+		assert.True(xxchan.ClaimPopMPSC((*xxchan.MPSC[int64])(sh.Channel)))
+	default:
+		assert.True(xxchan.ClaimPop(sh.Channel))
+	}
+	time.Sleep(time.Hour)
+}
@@ -0,0 +1,540 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const (
+	// registryNameMax is the longest channel name a Registry stores.
+	registryNameMax = 64
+
+	// registryAlign is the alignment of channel blocks in a registry's data
+	// area, a cache line, so that no two channels share one.
+	registryAlign = 64
+
+	// registryWait bounds how long a lookup waits for a channel that another
+	// goroutine or process is still creating under the same name.
+	registryWait = time.Second
+)
+
+// States of a registry entry.
+const (
+	entryReserved uint32 = iota // Name claimed, channel being created
+	entryReady                  // Channel created and visible to OpenNamed
+	entryRemoved                // Channel removed; the entry may be reused for the same name
+)
+
+var (
+	// ErrInvalidName is returned when a channel name is empty or longer than
+	// 64 bytes.
+	ErrInvalidName = errors.New("xxchan: invalid channel name")
+
+	// ErrNameExists is returned by CreateNamed when the registry already holds
+	// a channel with the name.
+	ErrNameExists = errors.New("xxchan: channel name already registered")
+
+	// ErrNameNotFound is returned by OpenNamed and Remove when the registry
+	// holds no channel with the name.
+	ErrNameNotFound = errors.New("xxchan: channel name not registered")
+
+	// ErrRegistryFull is returned by CreateNamed when the registry has no free
+	// entry or not enough free space left for the channel.
+	ErrRegistryFull = errors.New("xxchan: registry full")
+)
+
+// Registry is a table of named channels sharing one user-provided memory
+// block, so that processes mapping the same block can find their channels by
+// name instead of agreeing on offsets.
+//
+// The block holds a fixed number of entries followed by a data area from which
+// the channels themselves are carved. Each entry records a channel's name, the
+// offset of its block relative to the registry, and its capacity and element
+// fingerprint, so that OpenNamed can refuse a channel of the wrong type before
+// touching it. Offsets rather than pointers are stored, so the registry works
+// wherever each process happens to map it.
+//
+// Entries are claimed with a compare-and-swap on a hash of the name and found
+// again by probing from that hash, so CreateNamed, OpenNamed, List and Remove
+// may be called concurrently from any goroutine or process. Of several
+// concurrent CreateNamed calls for one name exactly one succeeds; the others
+// wait for it to finish and fail with ErrNameExists.
+//
+// Neither entries nor space are ever freed. An entry stays bound to the first
+// name that claimed it: Remove lets a channel of the same name be created
+// again, but the entry cannot be used for another name, so a registry needs as
+// many entries as distinct names it will ever hold. Likewise, a removed
+// channel's block is reused when a channel of the same name and no larger size
+// is created again, and otherwise stays unused. A registry therefore suits a
+// set of long-lived channels fixed by the application.
+//
+// An entry records the process creating its channel. If that process dies
+// before finishing, as when it is killed, the next CreateNamed for the name
+// takes the entry over, and OpenNamed and Remove no longer wait for it.
+// Detecting a dead process needs a Unix system; elsewhere, and if the process
+// dies in the few instructions between claiming a fresh entry and recording
+// itself, lookups of the name fail after waiting a second.
+//
+// Example usage:
+//
+//	size := xxchan.SizeofRegistry(16, 1<<20)
+//	buf := make([]byte, size)
+//	r := xxchan.MakeRegistry(unsafe.Pointer(&buf[0]), 16, 1<<20)
+//	jobs, err := xxchan.CreateNamed[int64](r, "jobs", 1024)
+//	same, err := xxchan.OpenNamed[int64](r, "jobs")
+type Registry struct {
+	header
+	entries int64 // Number of entries in the table
+	size    int64 // Size of the data area, a multiple of registryAlign
+	used    int64 // Bytes of the data area handed out so far
+}
+
+// registryEntry is an entry of a Registry's table.
+//
+// key is zero while the entry is free. It is claimed once, with a
+// compare-and-swap, and then never changes, so a probe sequence that reaches
+// a free entry has seen every entry for its name. The other fields are only
+// written while the entry is entryReserved by the goroutine that reserved it.
+type registryEntry struct {
+	key         uint64 // Hash of the name, with the low bit set so that it is never zero
+	owner       int64  // ID of the process holding the entry entryReserved, or 0
+	state       uint32
+	nameLen     uint32
+	name        [registryNameMax]byte
+	offset      int64 // Offset of the channel block from the start of the registry
+	size        int64 // Size of the space reserved for the block
+	cap         int64
+	fingerprint uint64
+}
+
+// RegistryEntry describes a channel listed by Registry.List.
+type RegistryEntry struct {
+	Name        string
+	Cap         int    // Capacity of the channel
+	Offset      int    // Offset of the channel block from the start of the registry
+	Size        int    // Bytes reserved for the channel block
+	Fingerprint uint64 // Structural fingerprint of the element type
+}
+
+// registrySize returns the sizes of the entry table and the data area of a
+// registry with the given number of entries and data area of at least n
+// bytes, panicking like Sizeof on invalid or overflowing arguments.
+func registrySize(entries, n int) (table, data int) {
+	if entries <= 0 || n < 0 {
+		panic(ErrInvalidCapacity)
+	}
+	limit := math.MaxInt - int(unsafe.Sizeof(Registry{})) - 2*registryAlign
+	if entries > limit/int(unsafe.Sizeof(registryEntry{})) {
+		panic(ErrSizeOverflow)
+	}
+	table = entries * int(unsafe.Sizeof(registryEntry{}))
+	if n > limit-table {
+		panic(ErrSizeOverflow)
+	}
+	return table, alignUp(n, registryAlign)
+}
+
+// SizeofRegistry calculates the total memory size required for a Registry
+// with the given number of entries and a data area of n bytes.
+//
+// Every channel takes Sizeof[T](cap, opts...) bytes of the data area, rounded
+// up to a multiple of 64.
+//
+// Parameters:
+//   - entries: The maximum number of channels the registry can name
+//   - n: The size of the data area the channels are created in
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the registry
+func SizeofRegistry(entries, n int) int {
+	table, data := registrySize(entries, n)
+	return alignUp(int(unsafe.Sizeof(Registry{}))+table, registryAlign) + data
+}
+
+// MakeRegistry initializes a new, empty Registry using a pre-allocated memory
+// block of at least SizeofRegistry(entries, n) bytes, aligned to 8 bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - entries: The maximum number of channels the registry can name
+//   - n: The size of the data area the channels are created in
+//
+// Returns:
+//   - A pointer to the initialized Registry
+//
+// The safety requirements of Make apply unchanged.
+func MakeRegistry(ptr unsafe.Pointer, entries, n int) *Registry {
+	_, data := registrySize(entries, n)
+	r := (*Registry)(ptr)
+	r.entries = int64(entries)
+	r.size = int64(data)
+	r.used = 0
+	clear(r.table())
+	r.header.init(layoutRegistry, unsafe.Sizeof(registryEntry{}), unsafe.Alignof(registryEntry{}), fingerprintOf[registryEntry]())
+	return r
+}
+
+// AttachRegistry returns the Registry that an earlier MakeRegistry
+// initialized in the memory block at ptr, without resetting it. The header is
+// checked as by Attach.
+func AttachRegistry(ptr unsafe.Pointer) (*Registry, error) {
+	r := (*Registry)(ptr)
+	if err := r.header.check(layoutRegistry, unsafe.Sizeof(registryEntry{}), unsafe.Alignof(registryEntry{}), fingerprintOf[registryEntry]()); err != nil {
+		return nil, err
+	}
+	limit := int64(math.MaxInt / 4)
+	if r.entries <= 0 || r.entries > limit/int64(unsafe.Sizeof(registryEntry{})) || r.size < 0 || r.size > limit || r.size%registryAlign != 0 {
+		return nil, fmt.Errorf("%w: invalid registry with %d entries and %d bytes", ErrNotChannel, r.entries, r.size)
+	}
+	return r, nil
+}
+
+// blockSize returns the size of the block r was made in.
+func (r *Registry) blockSize() int {
+	return r.dataOffset() + int(r.size)
+}
+
+// table returns the entry table, located immediately after the Registry
+// struct in memory.
+func (r *Registry) table() []registryEntry {
+	addr := unsafe.Add(unsafe.Pointer(r), unsafe.Sizeof(*r))
+	return unsafe.Slice((*registryEntry)(addr), r.entries)
+}
+
+// dataOffset returns the offset of the data area from the start of the
+// registry, just past the table.
+func (r *Registry) dataOffset() int {
+	return alignUp(int(unsafe.Sizeof(*r))+int(r.entries)*int(unsafe.Sizeof(registryEntry{})), registryAlign)
+}
+
+// at returns the address at offset off from the start of the registry.
+func (r *Registry) at(off int64) unsafe.Pointer {
+	return unsafe.Add(unsafe.Pointer(r), off)
+}
+
+// allocate hands out size bytes of the data area, returning their offset from
+// the start of the registry.
+func (r *Registry) allocate(size int64) (off int64, ok bool) {
+	size = int64(alignUp(int(size), registryAlign))
+	for {
+		used := atomic.LoadInt64(&r.used)
+		if size > r.size-used {
+			return 0, false
+		}
+		if atomic.CompareAndSwapInt64(&r.used, used, used+size) {
+			return int64(r.dataOffset()) + used, true
+		}
+	}
+}
+
+// registryKey hashes a channel name with FNV-1a.
+func registryKey(name string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(name); i++ {
+		h ^= uint64(name[i])
+		h *= 1099511628211
+	}
+	return h | 1
+}
+
+// checkName reports ErrInvalidName if name cannot be stored in a registry.
+func checkName(name string) error {
+	if name == "" || len(name) > registryNameMax {
+		return fmt.Errorf("%w: %q must be 1 to %d bytes long", ErrInvalidName, name, registryNameMax)
+	}
+	return nil
+}
+
+// settle returns the state of e once it is no longer entryReserved, or
+// entryReserved if the process creating the channel has died or does not
+// finish within registryWait.
+func (e *registryEntry) settle() uint32 {
+	var deadline time.Time
+	for {
+		state := atomic.LoadUint32(&e.state)
+		if state != entryReserved {
+			return state
+		}
+		if deadline.IsZero() {
+			if e.deadOwner() != 0 {
+				return state
+			}
+			deadline = time.Now().Add(registryWait)
+		} else if time.Now().After(deadline) {
+			return state
+		}
+		runtime.Gosched()
+	}
+}
+
+// deadOwner returns the ID of the process holding e entryReserved if that
+// process has exited, or 0.
+//
+// The owner is cleared before the entry leaves entryReserved, so a dead owner
+// can only be found while the entry is still reserved on its behalf.
+func (e *registryEntry) deadOwner() int64 {
+	owner := atomic.LoadInt64(&e.owner)
+	if owner == 0 || owner == selfPID || processAlive(int(owner)) {
+		return 0
+	}
+	return owner
+}
+
+// release hands e, reserved by this process, over to state.
+func (e *registryEntry) release(state uint32) {
+	atomic.StoreInt64(&e.owner, 0)
+	atomic.StoreUint32(&e.state, state)
+}
+
+// is reports whether e, which is no longer entryReserved, names name.
+func (e *registryEntry) is(name string) bool {
+	return string(e.name[:e.nameLen]) == name
+}
+
+// lookup returns the entry naming name, which is entryReady or entryRemoved,
+// or nil if there is none. The error reports a channel of that name whose
+// creation has not finished within registryWait.
+func (r *Registry) lookup(name string) (*registryEntry, error) {
+	key := registryKey(name)
+	table := r.table()
+	start := key % uint64(len(table))
+	for i := range uint64(len(table)) {
+		e := &table[(start+i)%uint64(len(table))]
+		switch atomic.LoadUint64(&e.key) {
+		case 0:
+			return nil, nil
+		case key:
+			if e.settle() == entryReserved {
+				if e.deadOwner() != 0 {
+					continue // Its creator died before the channel became visible
+				}
+				return nil, fmt.Errorf("%w: %q is still being created", ErrNameNotFound, name)
+			}
+			if e.is(name) {
+				return e, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// reserve claims an entry for a new channel named name, either a free one,
+// one whose channel of the same name was removed, or one left reserved by a
+// process that died, and returns it in state entryReserved.
+func (r *Registry) reserve(name string) (*registryEntry, error) {
+	key := registryKey(name)
+	table := r.table()
+	start := key % uint64(len(table))
+	for i := range uint64(len(table)) {
+		e := &table[(start+i)%uint64(len(table))]
+		k := atomic.LoadUint64(&e.key)
+		if k == 0 {
+			if atomic.CompareAndSwapUint64(&e.key, 0, key) {
+				atomic.StoreInt64(&e.owner, selfPID)
+				e.nameLen = uint32(copy(e.name[:], name))
+				return e, nil
+			}
+			k = atomic.LoadUint64(&e.key)
+		}
+		if k != key {
+			continue
+		}
+		for {
+			state := e.settle()
+			if state == entryReserved {
+				if dead := e.deadOwner(); dead != 0 && atomic.CompareAndSwapInt64(&e.owner, dead, selfPID) {
+					// The dead process may have left the entry half written.
+					// Its space is given up, as it may be smaller than e.size.
+					e.nameLen = uint32(copy(e.name[:], name))
+					e.size = 0
+					return e, nil
+				}
+				return nil, fmt.Errorf("%w: %q is still being created", ErrNameExists, name)
+			}
+			if !e.is(name) {
+				break
+			}
+			if state == entryReady {
+				return nil, fmt.Errorf("%w: %q", ErrNameExists, name)
+			}
+			if atomic.CompareAndSwapUint32(&e.state, entryRemoved, entryReserved) {
+				atomic.StoreInt64(&e.owner, selfPID)
+				return e, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: all %d entries are in use", ErrRegistryFull, len(table))
+}
+
+// CreateNamed creates a Channel[T] of capacity n in the registry's data area
+// and registers it under name.
+//
+// Like New, CreateNamed rejects element types that contain Go pointers unless
+// AllowPointers is given; a registry in memory shared between processes must
+// never hold them.
+//
+// Parameters:
+//   - r: The registry to create the channel in
+//   - name: The channel's name, 1 to 64 bytes long
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy, WithOverflow, WithPadding or
+//     WithRecovery, recorded in the channel as by Make
+//
+// Returns:
+//   - A pointer to the channel, valid for as long as the registry's memory
+//   - ErrInvalidName, ErrInvalidCapacity, ErrSizeOverflow, ErrPointerElement,
+//     ErrNameExists or ErrRegistryFull (wrapped with details) if the channel
+//     cannot be created
+//
+// Example:
+//
+//	ch, err := xxchan.CreateNamed[int64](r, "jobs", 1024, xxchan.WithLockStrategy(xxchan.LockFutexShared))
+func CreateNamed[T any](r *Registry, name string, n int, opts ...Option) (*Channel[T], error) {
+	o := newOptions(opts)
+	if !o.allowPointers {
+		if err := checkPointerFree[T](); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCapacity, n)
+	}
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		return nil, fmt.Errorf("%w: capacity %d", ErrSizeOverflow, n)
+	}
+	e, err := r.reserve(name)
+	if err != nil {
+		return nil, err
+	}
+	if int64(size) > e.size {
+		off, ok := r.allocate(int64(size))
+		if !ok {
+			// The entry stays claimed for name, so a later attempt can use it.
+			e.release(entryRemoved)
+			return nil, fmt.Errorf("%w: %d bytes needed for %q, %d of %d free",
+				ErrRegistryFull, size, name, r.size-atomic.LoadInt64(&r.used), r.size)
+		}
+		e.offset = off
+		e.size = int64(alignUp(size, registryAlign))
+	}
+	c := Make[T](r.at(e.offset), n, opts...)
+	e.cap = int64(n)
+	e.fingerprint = fingerprintOf[T]()
+	e.release(entryReady)
+	return c, nil
+}
+
+// OpenNamed returns the Channel[T] registered under name, possibly by another
+// process, without reinitializing it. The entry's fingerprint and the
+// channel's header are checked as by Attach.
+//
+// If another goroutine or process is creating a channel of that name at the
+// same moment, OpenNamed waits for it to finish.
+//
+// Parameters:
+//   - r: The registry holding the channel
+//   - name: The name the channel was created under
+//
+// Returns:
+//   - A pointer to the channel, valid for as long as the registry's memory
+//   - ErrNameNotFound if no channel has that name, or ErrTypeMismatch or
+//     ErrLayoutMismatch if it does not hold a Channel[T]
+func OpenNamed[T any](r *Registry, name string) (*Channel[T], error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	e, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || atomic.LoadUint32(&e.state) != entryReady {
+		return nil, fmt.Errorf("%w: %q", ErrNameNotFound, name)
+	}
+	if fp := fingerprintOf[T](); e.fingerprint != fp {
+		return nil, fmt.Errorf("%w: %q has fingerprint %#x, want %#x", ErrTypeMismatch, name, e.fingerprint, fp)
+	}
+	if e.offset < int64(r.dataOffset()) || e.size > int64(r.blockSize())-e.offset {
+		return nil, fmt.Errorf("%w: %q lies outside the registry", ErrNotChannel, name)
+	}
+	c, err := Attach[T](r.at(e.offset))
+	if err != nil {
+		return nil, err
+	}
+	if size, _ := sizeofMPMC[T](int(c.cap), c.stride, c.words); int64(size) > e.size {
+		return nil, fmt.Errorf("%w: %q needs %d bytes, has %d", ErrLayoutMismatch, name, size, e.size)
+	}
+	return c, nil
+}
+
+// List returns a snapshot of the channels in the registry, in table order.
+func (r *Registry) List() []RegistryEntry {
+	if r == nil {
+		return nil
+	}
+	var list []RegistryEntry
+	table := r.table()
+	for i := range table {
+		e := &table[i]
+		if atomic.LoadUint64(&e.key) == 0 || atomic.LoadUint32(&e.state) != entryReady {
+			continue
+		}
+		list = append(list, RegistryEntry{
+			Name:        string(e.name[:e.nameLen]),
+			Cap:         int(e.cap),
+			Offset:      int(e.offset),
+			Size:        int(e.size),
+			Fingerprint: e.fingerprint,
+		})
+	}
+	return list
+}
+
+// Remove unregisters the channel named name, so that OpenNamed no longer finds
+// it and CreateNamed may create a new channel under the name.
+//
+// Remove does not touch the channel itself. No goroutine or process may use
+// the channel once a new one has been created under the same name, since the
+// new channel may take over its memory; close it first to let users notice.
+//
+// Returns:
+//   - nil if the channel was removed
+//   - ErrInvalidName or ErrNameNotFound if no channel has that name
+func (r *Registry) Remove(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	e, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	if e == nil || !atomic.CompareAndSwapUint32(&e.state, entryReady, entryRemoved) {
+		return fmt.Errorf("%w: %q", ErrNameNotFound, name)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func newRegistry(t *testing.T, entries, n int) *xxchan.Registry {
+	ptr := alloc(uint(xxchan.SizeofRegistry(entries, n)))
+	t.Cleanup(func() { free(ptr) })
+	return xxchan.MakeRegistry(ptr, entries, n)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	r := newRegistry(t, 8, 4096)
+	assert.Empty(r.List())
+
+	jobs, err := xxchan.CreateNamed[int64](r, "jobs", 8)
+	assert.NoError(err)
+	_, err = xxchan.CreateNamed[[2]int32](r, "pairs", 3, xxchan.WithPadding())
+	assert.NoError(err)
+
+	same, err := xxchan.OpenNamed[int64](r, "jobs")
+	assert.NoError(err)
+	assert.Same(jobs, same)
+	assert.True(jobs.Push(42))
+	val, ok := same.Pop()
+	assert.True(ok)
+	assert.Equal(int64(42), val)
+
+	pairs, err := xxchan.OpenNamed[[2]int32](r, "pairs")
+	assert.NoError(err)
+	assert.Equal(3, pairs.Cap())
+	assert.True(pairs.Push([2]int32{1, 2}))
+
+	// Channels are carved out of the data area one cache line apart, and each
+	// keeps its own state.
+	list := r.List()
+	assert.Len(list, 2)
+	byName := map[string]xxchan.RegistryEntry{}
+	for _, e := range list {
+		byName[e.Name] = e
+	}
+	assert.Equal(8, byName["jobs"].Cap)
+	assert.Equal(3, byName["pairs"].Cap)
+	for _, e := range list {
+		assert.Zero(e.Offset % 64)
+		assert.Zero(e.Size % 64)
+	}
+	assert.Equal(0, jobs.Len())
+	assert.Equal(1, pairs.Len())
+
+	// The same block can be reattached, and finds the same channels.
+	again, err := xxchan.AttachRegistry(unsafe.Pointer(r))
+	assert.NoError(err)
+	reopened, err := xxchan.OpenNamed[int64](again, "jobs")
+	assert.NoError(err)
+	assert.Same(jobs, reopened)
+}
+
+func TestRegistryErrors(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	r := newRegistry(t, 2, 1024)
+	_, err := xxchan.CreateNamed[int64](r, "jobs", 8)
+	assert.NoError(err)
+
+	_, err = xxchan.CreateNamed[int64](r, "jobs", 8)
+	assert.ErrorIs(err, xxchan.ErrNameExists)
+	_, err = xxchan.OpenNamed[int64](r, "missing")
+	assert.ErrorIs(err, xxchan.ErrNameNotFound)
+	_, err = xxchan.OpenNamed[float64](r, "jobs")
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.OpenNamed[int32](r, "jobs")
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+
+	_, err = xxchan.CreateNamed[int64](r, "", 8)
+	assert.ErrorIs(err, xxchan.ErrInvalidName)
+	_, err = xxchan.CreateNamed[int64](r, string(make([]byte, 65)), 8)
+	assert.ErrorIs(err, xxchan.ErrInvalidName)
+	_, err = xxchan.CreateNamed[int64](r, "zero", 0)
+	assert.ErrorIs(err, xxchan.ErrInvalidCapacity)
+	_, err = xxchan.CreateNamed[*int64](r, "pointers", 8)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+
+	// The data area runs out before the table does.
+	_, err = xxchan.CreateNamed[int64](r, "big", 1000)
+	assert.ErrorIs(err, xxchan.ErrRegistryFull)
+	_, err = xxchan.OpenNamed[int64](r, "big")
+	assert.ErrorIs(err, xxchan.ErrNameNotFound)
+	_, err = xxchan.CreateNamed[int64](r, "big", 4)
+	assert.NoError(err)
+
+	// Both entries are taken now.
+	_, err = xxchan.CreateNamed[int64](r, "third", 1)
+	assert.ErrorIs(err, xxchan.ErrRegistryFull)
+
+	_, err = xxchan.AttachRegistry(unsafe.Pointer(&make([]int64, 16)[0]))
+	assert.ErrorIs(err, xxchan.ErrNotChannel)
+}
+
+func TestRegistryRemove(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	r := newRegistry(t, 4, 4096)
+	jobs, err := xxchan.CreateNamed[int64](r, "jobs", 16)
+	assert.NoError(err)
+	before := r.List()
+
+	assert.NoError(r.Remove("jobs"))
+	assert.ErrorIs(r.Remove("jobs"), xxchan.ErrNameNotFound)
+	assert.ErrorIs(r.Remove("missing"), xxchan.ErrNameNotFound)
+	_, err = xxchan.OpenNamed[int64](r, "jobs")
+	assert.ErrorIs(err, xxchan.ErrNameNotFound)
+	assert.Empty(r.List())
+
+	// A channel of the same name that fits takes over the removed block.
+	smaller, err := xxchan.CreateNamed[int64](r, "jobs", 8)
+	assert.NoError(err)
+	assert.Equal(unsafe.Pointer(jobs), unsafe.Pointer(smaller))
+	assert.Equal(8, smaller.Cap())
+	after := r.List()
+	assert.Len(after, 1)
+	assert.Equal(before[0].Offset, after[0].Offset)
+
+	// A larger one gets fresh space, under any element type.
+	assert.NoError(r.Remove("jobs"))
+	larger, err := xxchan.CreateNamed[[4]int64](r, "jobs", 32)
+	assert.NoError(err)
+	assert.NotEqual(unsafe.Pointer(jobs), unsafe.Pointer(larger))
+	_, err = xxchan.OpenNamed[int64](r, "jobs")
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+}
+
+func TestRegistryCreateRace(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	r := newRegistry(t, 16, 1<<16)
+	names := []string{"a", "b", "c", "d"}
+	workers := 8
+
+	var created, existed atomic.Int64
+	var failed atomic.Value
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for _, name := range names {
+				ch, err := xxchan.CreateNamed[int64](r, name, 16)
+				switch {
+				case err == nil:
+					created.Add(1)
+					ch.Push(1)
+				case errors.Is(err, xxchan.ErrNameExists):
+					existed.Add(1)
+				default:
+					failed.Store(err)
+				}
+				// Whoever lost the race finds the winner's channel.
+				if _, err := xxchan.OpenNamed[int64](r, name); err != nil {
+					failed.Store(err)
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Nil(failed.Load())
+	assert.Equal(int64(len(names)), created.Load())
+	assert.Equal(int64(len(names)*(workers-1)), existed.Load())
+	assert.Len(r.List(), len(names))
+	for _, name := range names {
+		ch, err := xxchan.OpenNamed[int64](r, name)
+		assert.NoError(err)
+		assert.Equal(1, ch.Len())
+	}
+}
+
+func TestSizeofRegistry(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// Growing the data area by a cache line adds exactly a cache line.
+	assert.Equal(xxchan.SizeofRegistry(4, 64)+64, xxchan.SizeofRegistry(4, 128))
+	assert.Equal(xxchan.SizeofRegistry(4, 64), xxchan.SizeofRegistry(4, 1))
+	assert.PanicsWithValue(xxchan.ErrInvalidCapacity, func() { xxchan.SizeofRegistry(0, 64) })
+	assert.PanicsWithValue(xxchan.ErrInvalidCapacity, func() { xxchan.SizeofRegistry(4, -1) })
+	assert.PanicsWithValue(xxchan.ErrSizeOverflow, func() { xxchan.SizeofRegistry(math.MaxInt/64, 64) })
+}
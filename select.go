@@ -0,0 +1,255 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// caseKind identifies the operation a Case performs.
+type caseKind uint8
+
+const (
+	caseDefault caseKind = iota
+	casePush
+	casePop
+)
+
+// selectOp is the type-erased view of a push or pop case, so that a single
+// Select call can wait on channels of different element types.
+type selectOp interface {
+	// probe reports whether the operation could proceed right now, without
+	// performing it.
+	probe() bool
+	// try performs the operation, failing with ErrFull or ErrEmpty if it can
+	// no longer proceed.
+	try() error
+	// events returns the notifier of the underlying channel.
+	events() *notifier
+}
+
+// Case is one arm of a Select call.
+//
+// Cases are built with PushCase, PopCase and DefaultCase. Building a case
+// allocates a small adapter, so a slice of cases is meant to be built once and
+// reused across Select calls, which themselves do not allocate.
+type Case struct {
+	kind caseKind
+	op   selectOp
+	seq  int32 // Notifier sequence snapshot while Select is waiting
+}
+
+// PushCase returns a case that pushes *src into ch when selected.
+//
+// src is read at the time the case is chosen, so the same case can send a
+// different value on every Select call. A case on a nil channel never proceeds,
+// like a send on a nil built-in channel.
+func PushCase[T any](ch *Channel[T], src *T) Case {
+	if ch == nil {
+		return Case{kind: casePush}
+	}
+	return Case{kind: casePush, op: &pushCase[T]{c: ch, src: src}}
+}
+
+// PopCase returns a case that pops a value from ch into *dst when selected.
+//
+// If ch is closed and drained, the case is ready and Select reports ErrClosed
+// for it, like a receive from a closed built-in channel. A case on a nil
+// channel never proceeds.
+func PopCase[T any](ch *Channel[T], dst *T) Case {
+	if ch == nil {
+		return Case{kind: casePop}
+	}
+	return Case{kind: casePop, op: &popCase[T]{c: ch, dst: dst}}
+}
+
+// DefaultCase returns a case that is chosen when no other case can proceed
+// immediately, like the default clause of a select statement.
+func DefaultCase() Case {
+	return Case{kind: caseDefault}
+}
+
+// Select waits until one of the cases can proceed, performs it, and returns
+// its index.
+//
+// When several cases are ready, Select chooses one uniformly at random, as the
+// select statement does. If none is ready and cases contain a DefaultCase, its
+// index is returned immediately; otherwise Select waits until a case proceeds
+// or ctx is done, like PopWait on the first case's channel, in the way its
+// LockStrategy selects. With futex strategies, cases on other channels are
+// then checked every millisecond.
+//
+// Parameters:
+//   - ctx: Context that bounds the wait
+//   - cases: The cases to select from; at most one may be a DefaultCase
+//
+// Returns:
+//   - chosen: Index of the case that proceeded, or -1 if ctx was done
+//   - err: nil on success, ErrClosed if the chosen case's channel is closed
+//     (and drained, for a pop case), or ctx.Err()
+//
+// Select panics if cases contain more than one DefaultCase.
+func Select(ctx context.Context, cases []Case) (chosen int, err error) {
+	def := -1
+	for i := range cases {
+		if cases[i].kind == caseDefault {
+			if def >= 0 {
+				panic("xxchan: Select with multiple default cases")
+			}
+			def = i
+		}
+	}
+	for {
+		if chosen, err = trySelect(cases); chosen >= 0 {
+			return
+		}
+		if def >= 0 {
+			return def, nil
+		}
+		// Register with every channel, then look once more so that a change
+		// landing in between is not missed.
+		for i := range cases {
+			if op := cases[i].op; op != nil {
+				cases[i].seq = op.events().watch()
+			}
+		}
+		if chosen, err = trySelect(cases); chosen < 0 {
+			err = waitCases(ctx, cases)
+		}
+		for i := range cases {
+			if op := cases[i].op; op != nil {
+				op.events().unwatch()
+			}
+		}
+		if chosen >= 0 || err != nil {
+			return
+		}
+	}
+}
+
+// trySelect performs one ready case, chosen uniformly at random, and returns
+// its index, or -1 if no case is ready.
+func trySelect(cases []Case) (int, error) {
+	for {
+		// Reservoir-sample one ready case so every ready case is equally likely.
+		chosen, ready := -1, 0
+		for i := range cases {
+			if op := cases[i].op; op != nil && op.probe() {
+				ready++
+				if rand.IntN(ready) == 0 {
+					chosen = i
+				}
+			}
+		}
+		if chosen < 0 {
+			return -1, nil
+		}
+		if err := cases[chosen].op.try(); err != ErrFull && err != ErrEmpty {
+			return chosen, err
+		}
+		// Lost a race for the chosen case; sample again
+	}
+}
+
+// waitCases blocks until the notifier of any case moves past its snapshot, or
+// ctx is done.
+//
+// It waits like notifier.wait on the first case's channel, in the way that
+// channel's LockStrategy selects. A futex sleep can only watch one channel, so
+// with cases on several channels it is bounded by futexPoll, within which a
+// change on another channel is noticed.
+func waitCases(ctx context.Context, cases []Case) error {
+	done := ctx.Done()
+	var (
+		n      *notifier
+		seq    int32
+		others bool
+	)
+	for j := range cases {
+		switch op := cases[j].op; {
+		case op == nil:
+		case n == nil:
+			n, seq = op.events(), cases[j].seq
+		case op.events() != n:
+			others = true
+		}
+	}
+	if n == nil {
+		// Only nil channels, which never proceed
+		<-done
+		return ctx.Err()
+	}
+	for i := 0; ; i++ {
+		for j := range cases {
+			if op := cases[j].op; op != nil && atomic.LoadInt32(&op.events().l) != cases[j].seq {
+				return nil
+			}
+		}
+		if !n.step(i) {
+			continue
+		}
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+		n.sleep(i, seq, others || done != nil)
+	}
+}
+
+// pushCase adapts a Channel[T] push to selectOp.
+type pushCase[T any] struct {
+	c   *Channel[T]
+	src *T
+}
+
+func (p *pushCase[T]) probe() bool {
+	return p.c.canPush()
+}
+
+func (p *pushCase[T]) try() error {
+	return p.c.push(*p.src)
+}
+
+func (p *pushCase[T]) events() *notifier {
+	return p.c.events()
+}
+
+// popCase adapts a Channel[T] pop to selectOp.
+type popCase[T any] struct {
+	c   *Channel[T]
+	dst *T
+}
+
+func (p *popCase[T]) probe() bool {
+	return p.c.canPop()
+}
+
+func (p *popCase[T]) try() error {
+	v, err := p.c.pop()
+	if err == nil {
+		*p.dst = v
+	}
+	return err
+}
+
+func (p *popCase[T]) events() *notifier {
+	return p.c.events()
+}
@@ -0,0 +1,212 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 2
+	dataPtr := alloc(uint(xxchan.Sizeof[int](n)))
+	ctrlPtr := alloc(uint(xxchan.Sizeof[string](n)))
+	t.Cleanup(func() {
+		free(dataPtr)
+		free(ctrlPtr)
+	})
+
+	data := xxchan.Make[int](dataPtr, n)
+	ctrl := xxchan.Make[string](ctrlPtr, n)
+
+	var (
+		v   int
+		cmd string
+	)
+	cases := []xxchan.Case{
+		xxchan.PopCase(data, &v),
+		xxchan.PopCase(ctrl, &cmd),
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ctrl.Push("stop")
+	}()
+	chosen, err := xxchan.Select(context.Background(), cases)
+	assert.NoError(err)
+	assert.Equal(1, chosen)
+	assert.Equal("stop", cmd)
+
+	assert.True(data.Push(42))
+	chosen, err = xxchan.Select(context.Background(), cases)
+	assert.NoError(err)
+	assert.Equal(0, chosen)
+	assert.Equal(42, v)
+
+	assert.NoError(data.Close())
+	chosen, err = xxchan.Select(context.Background(), cases)
+	assert.ErrorIs(err, xxchan.ErrClosed)
+	assert.Equal(0, chosen)
+}
+
+func TestSelectLockStrategy(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range lockStrategies {
+		t.Run(strategy.String(), func(t *testing.T) {
+			t.Parallel()
+			assert := require.New(t)
+
+			if strategy == xxchan.LockSpin && runtime.GOMAXPROCS(0) < 2 {
+				t.Skip("LockSpin needs a processor per goroutine")
+			}
+
+			n := 1
+			firstPtr := alloc(uint(xxchan.Sizeof[int](n)))
+			secondPtr := alloc(uint(xxchan.Sizeof[int](n)))
+			t.Cleanup(func() {
+				free(firstPtr)
+				free(secondPtr)
+			})
+
+			first := xxchan.Make[int](firstPtr, n, xxchan.WithLockStrategy(strategy))
+			second := xxchan.Make[int](secondPtr, n, xxchan.WithLockStrategy(strategy))
+
+			var v int
+			cases := []xxchan.Case{
+				xxchan.PopCase(first, &v),
+				xxchan.PopCase(second, &v),
+			}
+
+			// Select sleeps on the first channel, and still notices the second.
+			for i, ch := range []*xxchan.Channel[int]{first, second, first} {
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					ch.Push(i)
+				}()
+				chosen, err := xxchan.Select(context.Background(), cases)
+				assert.NoError(err)
+				assert.Equal(i%2, chosen)
+				assert.Equal(i, v)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			chosen, err := xxchan.Select(ctx, cases)
+			assert.ErrorIs(err, context.DeadlineExceeded)
+			assert.Equal(-1, chosen)
+		})
+	}
+}
+
+func TestSelectPushDefault(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	v := 7
+	cases := []xxchan.Case{
+		xxchan.PushCase(ch, &v),
+		xxchan.DefaultCase(),
+	}
+
+	chosen, err := xxchan.Select(context.Background(), cases)
+	assert.NoError(err)
+	assert.Equal(0, chosen)
+	assert.Equal(1, ch.Len())
+
+	chosen, err = xxchan.Select(context.Background(), cases)
+	assert.NoError(err)
+	assert.Equal(1, chosen)
+	assert.Equal(1, ch.Len())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	chosen, err = xxchan.Select(ctx, cases[:1])
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(-1, chosen)
+
+	assert.Panics(func() {
+		_, _ = xxchan.Select(context.Background(), []xxchan.Case{
+			xxchan.DefaultCase(), xxchan.DefaultCase(),
+		})
+	})
+}
+
+func TestSelectFair(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	total := 3000
+	chans := make([]*xxchan.Channel[int], n)
+	cases := make([]xxchan.Case, n)
+	var v int
+	for i := range chans {
+		ptr := alloc(uint(xxchan.Sizeof[int](1)))
+		t.Cleanup(func() { free(ptr) })
+		chans[i] = xxchan.Make[int](ptr, 1)
+		cases[i] = xxchan.PopCase(chans[i], &v)
+	}
+
+	counts := make([]int, n)
+	for range total {
+		for _, ch := range chans {
+			ch.Push(1)
+		}
+		chosen, err := xxchan.Select(context.Background(), cases)
+		assert.NoError(err)
+		counts[chosen]++
+	}
+	for _, c := range counts {
+		assert.InDelta(total/n, c, float64(total/n)/3)
+	}
+}
+
+func TestSelectAllocs(t *testing.T) {
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+
+	var in, out int
+	cases := []xxchan.Case{
+		xxchan.PushCase(ch, &in),
+		xxchan.PopCase(ch, &out),
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = xxchan.Select(context.Background(), cases)
+	})
+	assert.Equal(0.0, allocs)
+}
@@ -0,0 +1,349 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build unix
+
+package xxchan
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// Shared is a Channel[T] that lives in a memory-mapped file, so that several
+// independent processes can push to and pop from the same ring.
+//
+// All of Channel[T]'s state, including the words used by PushWait and PopWait,
+// lives in the mapping, and every operation is built on atomic instructions
+// that work on shared memory just as they do within one process. Created with
+// the LockFutexShared strategy, a channel lets PushWait and PopWait sleep in
+// the kernel on Linux and be woken by a Push or Pop in any process. T must not
+// contain pointers, since they would be meaningless in another address space;
+// Create and Open reject such types with ErrPointerElement.
+//
+// Example usage:
+//
+//	// Process A
+//	sh, err := xxchan.Create[int64]("/dev/shm/jobs", 1024, xxchan.WithLockStrategy(xxchan.LockFutexShared))
+//	defer sh.Unmap()
+//	sh.Push(42)
+//
+//	// Process B
+//	sh, err := xxchan.Open[int64]("/dev/shm/jobs")
+//	defer sh.Unmap()
+//	v, ok := sh.Pop()
+type Shared[T any] struct {
+	*Channel[T]
+	data []byte
+}
+
+// Create creates a file at path, sizes it for a Channel[T] of capacity n,
+// maps it into memory and initializes the channel in it.
+//
+// Placing the file under /dev/shm keeps it in memory without ever being
+// written back to disk. Create fails if the file already exists.
+//
+// The options are recorded in the channel, so processes that Open it later
+// share the creator's overflow policy, lock strategy and layout.
+//
+// Parameters:
+//   - path: Path of the file to create, typically under /dev/shm
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy(LockFutexShared), WithOverflow
+//     or WithPadding; AllowPointers has no effect
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - An error if the file cannot be created or mapped, ErrInvalidCapacity or
+//     ErrSizeOverflow (wrapped with details) if n is out of range, or
+//     ErrPointerElement if T contains Go pointers
+func Create[T any](path string, n int, opts ...Option) (*Shared[T], error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCapacity, n)
+	}
+	o := newOptions(opts)
+	size, ok := sizeofMPMC[T](n, o.stride(), o.slotWords())
+	if !ok {
+		return nil, fmt.Errorf("%w: capacity %d", ErrSizeOverflow, n)
+	}
+	data, err := createFile(path, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Shared[T]{Channel: Make[T](unsafe.Pointer(&data[0]), n, opts...), data: data}, nil
+}
+
+// createFile creates a file of size bytes at path, failing if it exists, and
+// maps it. The file is removed again if it cannot be mapped.
+func createFile(path string, size int) ([]byte, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(int64(size)); err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	data, err := mapFile(int(f.Fd()), size)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return data, nil
+}
+
+// Open maps an existing channel file created by Create, possibly in another
+// process, and attaches to the channel in it without reinitializing it.
+// The header written by Create is checked as by Attach.
+//
+// Parameters:
+//   - path: Path of the file passed to Create
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - An error if the file cannot be mapped, ErrNotChannel, ErrLayoutMismatch
+//     or ErrTypeMismatch if it does not hold a Channel[T], or
+//     ErrPointerElement if T contains Go pointers
+func Open[T any](path string) (*Shared[T], error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return attachFile[T](int(f.Fd()), path)
+}
+
+// FromFD maps the channel file open as fd, such as a memory file made by
+// NewMemfd and inherited through exec.Cmd.ExtraFiles or received with
+// ReceiveFD, and attaches to the channel in it without reinitializing it. The
+// header is checked as by Attach.
+//
+// FromFD does not take ownership of fd: the mapping stays valid after fd is
+// closed, and closing it remains up to the caller.
+//
+// Parameters:
+//   - fd: A file descriptor open for reading and writing
+//
+// Returns:
+//   - The mapped channel, which must be released with Unmap
+//   - An error if the file cannot be mapped, ErrNotChannel, ErrLayoutMismatch
+//     or ErrTypeMismatch if it does not hold a Channel[T], or
+//     ErrPointerElement if T contains Go pointers
+//
+// Example:
+//
+//	// Child process started with cmd.ExtraFiles = []*os.File{memfd}
+//	sh, err := xxchan.FromFD[int64](3)
+func FromFD[T any](fd uintptr) (*Shared[T], error) {
+	if err := checkPointerFree[T](); err != nil {
+		return nil, err
+	}
+	return attachFile[T](int(fd), "fd "+strconv.Itoa(int(fd)))
+}
+
+// attachFile maps the whole file open as fd and attaches to the Channel[T] in
+// it, checking that the file is exactly as large as the channel. name
+// describes the file in errors.
+func attachFile[T any](fd int, name string) (*Shared[T], error) {
+	data, err := mapWhole(fd, name, int(unsafe.Sizeof(Channel[T]{})))
+	if err != nil {
+		return nil, err
+	}
+	c, err := Attach[T](unsafe.Pointer(&data[0]))
+	if err == nil {
+		if want, _ := sizeofMPMC[T](int(c.cap), c.stride, c.words); want != len(data) {
+			err = fmt.Errorf("%w: %s is %d bytes, want %d", ErrLayoutMismatch, name, len(data), want)
+		}
+	}
+	if err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	return &Shared[T]{Channel: c, data: data}, nil
+}
+
+// mapWhole maps the whole file open as fd, refusing files smaller than min
+// bytes, which cannot hold what the caller looks for.
+func mapWhole(fd int, name string, min int) ([]byte, error) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return nil, &os.PathError{Op: "fstat", Path: name, Err: err}
+	}
+	if st.Size < int64(min) {
+		return nil, fmt.Errorf("%w: %s is too small", ErrNotChannel, name)
+	}
+	return mapFile(fd, int(st.Size))
+}
+
+// mapFile maps the first size bytes of the file open as fd for reading and
+// writing, shared with every other process that maps the same file.
+func mapFile(fd, size int) ([]byte, error) {
+	return syscall.Mmap(fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// SendFD passes the file f, typically made by NewMemfd, over the Unix domain
+// socket conn with an SCM_RIGHTS control message. The receiving process gets
+// its own descriptor for the same file with ReceiveFD; f can be closed once
+// SendFD returns.
+func SendFD(conn *net.UnixConn, f *os.File) error {
+	_, _, err := conn.WriteMsgUnix([]byte{0}, syscall.UnixRights(int(f.Fd())), nil)
+	return err
+}
+
+// ReceiveFD receives a file sent with SendFD over the Unix domain socket conn.
+// Pass its Fd to FromFD to map the channel in it, and close it when done.
+func ReceiveFD(conn *net.UnixConn) (*os.File, error) {
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		fds, err := syscall.ParseUnixRights(&msg)
+		if err != nil || len(fds) == 0 {
+			continue
+		}
+		for _, extra := range fds[1:] {
+			_ = syscall.Close(extra)
+		}
+		return os.NewFile(uintptr(fds[0]), "xxchan-fd"), nil
+	}
+	return nil, fmt.Errorf("xxchan: no file descriptor received on %s", conn.LocalAddr())
+}
+
+// Unmap releases the mapping. The channel must not be used afterwards.
+// The file itself is left in place; remove it with os.Remove once no process
+// needs the channel any more. A memory file made by NewMemfd disappears once
+// every process has unmapped it and closed its descriptors.
+func (s *Shared[T]) Unmap() error {
+	if s == nil || s.data == nil {
+		return nil
+	}
+	data := s.data
+	s.data = nil
+	s.Channel = nil
+	return syscall.Munmap(data)
+}
+
+// SharedRegistry is a Registry that lives in a memory-mapped file, so that
+// independent processes can create and find channels in it by name.
+//
+// Channels returned by CreateNamed and OpenNamed for a SharedRegistry live in
+// its mapping and must not be used after Unmap. As with Create and Open, their
+// element types must not contain pointers.
+//
+// Example usage:
+//
+//	// Process A
+//	r, err := xxchan.CreateRegistry("/dev/shm/app", 16, 1<<20)
+//	defer r.Unmap()
+//	jobs, err := xxchan.CreateNamed[int64](r.Registry, "jobs", 1024)
+//
+//	// Process B
+//	r, err := xxchan.OpenRegistry("/dev/shm/app")
+//	defer r.Unmap()
+//	jobs, err := xxchan.OpenNamed[int64](r.Registry, "jobs")
+type SharedRegistry struct {
+	*Registry
+	data []byte
+}
+
+// CreateRegistry creates a file at path, sizes it for a Registry with the
+// given number of entries and a data area of n bytes, maps it into memory and
+// initializes the registry in it. CreateRegistry fails if the file already
+// exists.
+//
+// Parameters:
+//   - path: Path of the file to create, typically under /dev/shm
+//   - entries: The maximum number of channels the registry can name
+//   - n: The size of the data area the channels are created in
+//
+// Returns:
+//   - The mapped registry, which must be released with Unmap
+//   - An error if the file cannot be created or mapped
+func CreateRegistry(path string, entries, n int) (*SharedRegistry, error) {
+	if entries <= 0 || n < 0 {
+		return nil, fmt.Errorf("%w: %d entries, %d bytes", ErrInvalidCapacity, entries, n)
+	}
+	data, err := createFile(path, SizeofRegistry(entries, n))
+	if err != nil {
+		return nil, err
+	}
+	return &SharedRegistry{Registry: MakeRegistry(unsafe.Pointer(&data[0]), entries, n), data: data}, nil
+}
+
+// OpenRegistry maps an existing registry file created by CreateRegistry,
+// possibly in another process, and attaches to the registry in it without
+// reinitializing it.
+//
+// Parameters:
+//   - path: Path of the file passed to CreateRegistry
+//
+// Returns:
+//   - The mapped registry, which must be released with Unmap
+//   - An error if the file cannot be mapped, or ErrNotChannel or
+//     ErrLayoutMismatch if it does not hold a Registry
+func OpenRegistry(path string) (*SharedRegistry, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := mapWhole(int(f.Fd()), path, int(unsafe.Sizeof(Registry{})))
+	if err != nil {
+		return nil, err
+	}
+	r, err := AttachRegistry(unsafe.Pointer(&data[0]))
+	if err == nil && r.blockSize() != len(data) {
+		err = fmt.Errorf("%w: %s is %d bytes, want %d", ErrLayoutMismatch, path, len(data), r.blockSize())
+	}
+	if err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	return &SharedRegistry{Registry: r, data: data}, nil
+}
+
+// Unmap releases the mapping, and with it every channel in the registry. The
+// file itself is left in place; remove it with os.Remove once no process needs
+// the registry any more.
+func (s *SharedRegistry) Unmap() error {
+	if s == nil || s.data == nil {
+		return nil
+	}
+	data := s.data
+	s.data = nil
+	s.Registry = nil
+	return syscall.Munmap(data)
+}
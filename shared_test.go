@@ -0,0 +1,277 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build unix
+
+package xxchan_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+// sharedPath returns a fresh path for a channel file, under /dev/shm when the
+// system has it.
+func sharedPath(t *testing.T) string {
+	dir := t.TempDir()
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		dir = "/dev/shm"
+	}
+	path := filepath.Join(dir, "xxchan-"+strconv.Itoa(os.Getpid())+"-"+t.Name())
+	t.Cleanup(func() { _ = os.Remove(path) })
+	return path
+}
+
+// helperProcess starts the test binary again, running only the named test
+// with the given environment.
+func helperProcess(t *testing.T, name string, env ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$", "-test.count=1")
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+func TestShared(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	n := 4
+
+	sh, err := xxchan.Create[int64](path, n)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	_, err = xxchan.Create[int64](path, n)
+	assert.ErrorIs(err, os.ErrExist)
+
+	other, err := xxchan.Open[int64](path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = other.Unmap() })
+	assert.Equal(n, other.Cap())
+
+	assert.True(sh.Push(7))
+	val, ok := other.Pop()
+	assert.True(ok)
+	assert.Equal(int64(7), val)
+
+	_, err = xxchan.Open[[4]int64](path)
+	assert.Error(err)
+	_, err = xxchan.Open[float64](path)
+	assert.ErrorIs(err, xxchan.ErrTypeMismatch)
+	_, err = xxchan.Open[*int64](path)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	_, err = xxchan.Create[string](path+"-string", n)
+	assert.ErrorIs(err, xxchan.ErrPointerElement)
+	_, err = xxchan.Create[int64](path+"-huge", math.MaxInt/2)
+	assert.ErrorIs(err, xxchan.ErrSizeOverflow)
+	_, err = os.Stat(path + "-huge")
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestSharedCrossProcess(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	total := 2000
+
+	sh, err := xxchan.Create[int64](path, 64)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap() })
+
+	cmd := helperProcess(t, "TestSharedProducerProcess",
+		"XXCHAN_SHARED_PATH="+path, "XXCHAN_SHARED_TOTAL="+strconv.Itoa(total))
+	assert.NoError(cmd.Start())
+
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		val, err := sh.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			assert.Equal(total, i)
+			break
+		}
+		assert.Equal(int64(i), val)
+	}
+	assert.NoError(cmd.Wait())
+}
+
+// TestSharedProducerProcess runs in the child process started by
+// TestSharedCrossProcess.
+func TestSharedProducerProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_SHARED_PATH")
+	if path == "" {
+		t.Skip("helper process for TestSharedCrossProcess")
+	}
+	assert := require.New(t)
+	total, err := strconv.Atoi(os.Getenv("XXCHAN_SHARED_TOTAL"))
+	assert.NoError(err)
+
+	sh, err := xxchan.Open[int64](path)
+	assert.NoError(err)
+	defer sh.Unmap()
+
+	ctx := context.Background()
+	for i := range total {
+		assert.NoError(sh.PushWait(ctx, int64(i)))
+	}
+	assert.NoError(sh.Close())
+}
+
+func TestSharedRegistry(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	total := 2000
+
+	r, err := xxchan.CreateRegistry(path, 8, 1<<16)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = r.Unmap() })
+	_, err = xxchan.CreateRegistry(path, 8, 1<<16)
+	assert.ErrorIs(err, os.ErrExist)
+
+	jobs, err := xxchan.CreateNamed[int64](r.Registry, "jobs", 64, xxchan.WithLockStrategy(xxchan.LockFutexShared))
+	assert.NoError(err)
+
+	// A second mapping sees the same table at a different address.
+	other, err := xxchan.OpenRegistry(path)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = other.Unmap() })
+	assert.Equal(r.List(), other.List())
+
+	sh, err := xxchan.Create[int64](path+"-channel", 4)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = sh.Unmap(); _ = os.Remove(path + "-channel") })
+	_, err = xxchan.OpenRegistry(path + "-channel")
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+	_, err = xxchan.Open[int64](path)
+	assert.ErrorIs(err, xxchan.ErrLayoutMismatch)
+
+	cmd := helperProcess(t, "TestSharedRegistryProcess",
+		"XXCHAN_REGISTRY_PATH="+path, "XXCHAN_REGISTRY_TOTAL="+strconv.Itoa(total))
+	assert.NoError(cmd.Start())
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		val, err := jobs.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			assert.Equal(total, i)
+			break
+		}
+		assert.Equal(int64(i), val)
+	}
+	assert.NoError(cmd.Wait())
+
+	// The child registered a channel of its own.
+	done, err := xxchan.OpenNamed[int64](r.Registry, "done")
+	assert.NoError(err)
+	val, ok := done.Pop()
+	assert.True(ok)
+	assert.Equal(int64(total), val)
+}
+
+// TestSharedRegistryProcess runs in the child process started by
+// TestSharedRegistry.
+func TestSharedRegistryProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_REGISTRY_PATH")
+	if path == "" {
+		t.Skip("helper process for TestSharedRegistry")
+	}
+	assert := require.New(t)
+	total, err := strconv.Atoi(os.Getenv("XXCHAN_REGISTRY_TOTAL"))
+	assert.NoError(err)
+
+	r, err := xxchan.OpenRegistry(path)
+	assert.NoError(err)
+	defer r.Unmap()
+
+	done, err := xxchan.CreateNamed[int64](r.Registry, "done", 1)
+	assert.NoError(err)
+	assert.True(done.Push(int64(total)))
+
+	jobs, err := xxchan.OpenNamed[int64](r.Registry, "jobs")
+	assert.NoError(err)
+	ctx := context.Background()
+	for i := range total {
+		assert.NoError(jobs.PushWait(ctx, int64(i)))
+	}
+	assert.NoError(jobs.Close())
+}
+
+func TestSharedRegistryDeadCreator(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	path := sharedPath(t)
+	r, err := xxchan.CreateRegistry(path, 4, 4096)
+	assert.NoError(err)
+	t.Cleanup(func() { _ = r.Unmap() })
+
+	cmd := helperProcess(t, "TestSharedRegistryReserveProcess", "XXCHAN_RESERVE_PATH="+path)
+	assert.NoError(cmd.Start())
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+	deadline := time.Now().Add(time.Minute)
+	for xxchan.NamedOwner(r.Registry, "jobs") != int64(cmd.Process.Pid) {
+		assert.True(time.Now().Before(deadline), "child did not reserve the name")
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(cmd.Process.Signal(syscall.SIGKILL))
+	_ = cmd.Wait()
+
+	// Lookups stop waiting for the dead creator, and the name can be created.
+	start := time.Now()
+	_, err = xxchan.OpenNamed[int64](r.Registry, "jobs")
+	assert.ErrorIs(err, xxchan.ErrNameNotFound)
+	assert.ErrorIs(r.Remove("jobs"), xxchan.ErrNameNotFound)
+	assert.Less(time.Since(start), 500*time.Millisecond)
+
+	jobs, err := xxchan.CreateNamed[int64](r.Registry, "jobs", 8)
+	assert.NoError(err)
+	assert.Zero(xxchan.NamedOwner(r.Registry, "jobs"))
+	same, err := xxchan.OpenNamed[int64](r.Registry, "jobs")
+	assert.NoError(err)
+	assert.Same(jobs, same)
+	assert.Len(r.List(), 1)
+}
+
+// TestSharedRegistryReserveProcess runs in the child process started by
+// TestSharedRegistryDeadCreator. It reserves a name and waits to be killed.
+func TestSharedRegistryReserveProcess(t *testing.T) {
+	path := os.Getenv("XXCHAN_RESERVE_PATH")
+	if path == "" {
+		t.Skip("helper process for TestSharedRegistryDeadCreator")
+	}
+	r, err := xxchan.OpenRegistry(path)
+	require.NoError(t, err)
+	require.NoError(t, xxchan.ReserveNamed(r.Registry, "jobs"))
+	time.Sleep(time.Minute)
+}
@@ -0,0 +1,368 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"context"
+	"sync/atomic"
+	"unsafe"
+)
+
+// SPSC is a single-producer single-consumer variant of Channel[T].
+//
+// It has the same method set as Channel[T] and lives in the same kind of
+// user-provided memory block, but assumes that at most one goroutine pushes
+// and at most one goroutine pops at any time. Under that contract both Push
+// and Pop are wait-free: each side owns its own position, keeps a cached copy
+// of the other side's position, and only reads the other side's cache line
+// when the cached copy says the ring looks full or empty.
+//
+// The producer's tail and cached head, and the consumer's head and cached
+// tail, each sit on a 128-byte line of their own, as with WithPadding, so
+// neither side's writes invalidate the line the other side is working on.
+//
+// Len, Cap, Closed and Close may be called from any goroutine.
+//
+// Example usage:
+//
+//	size := xxchan.SizeofSPSC[int](100)
+//	buf := make([]byte, size)
+//	ch := xxchan.MakeSPSC[int](unsafe.Pointer(&buf[0]), 100)
+type SPSC[T any] struct {
+	header
+	notifier
+	cap  int64
+	mask int64 // cap-1 if cap is a power of two, otherwise -1; see index
+	_    [paddedStride]byte
+
+	// Written by the producer.
+	tail      int64 // Producer position, with closedBit set once closed
+	headCache int64 // Producer's last observed head
+	_         [paddedStride - 16]byte
+
+	// Written by the consumer.
+	head      int64 // Consumer position
+	tailCache int64 // Consumer's last observed tail
+	_         [paddedStride - 16]byte
+
+	_ [0]T // Zero-sized placeholder for type information; actual buffer follows the struct
+}
+
+// SizeofSPSC calculates the total memory size required for an SPSC[T] with
+// the specified capacity.
+//
+// Parameters:
+//   - n: The desired capacity of the channel (maximum number of elements)
+//
+// Returns:
+//   - The total size in bytes that should be allocated for the channel
+//
+// Like Sizeof, SizeofSPSC panics if n is negative or the size overflows int.
+func SizeofSPSC[T any](n int) int {
+	if n < 0 {
+		panic(ErrInvalidCapacity)
+	}
+	size, ok := sizeofChannel(unsafe.Sizeof(SPSC[T]{}), 0, unsafe.Sizeof(*new(T)), n)
+	if !ok {
+		panic(ErrSizeOverflow)
+	}
+	return alignUp(size, int(unsafe.Alignof(SPSC[T]{})))
+}
+
+// MakeSPSC initializes a new SPSC[T] using a pre-allocated memory block of at
+// least SizeofSPSC[T](n) bytes.
+//
+// Parameters:
+//   - ptr: Pointer to the pre-allocated memory block
+//   - n: The capacity of the channel (maximum number of elements)
+//   - opts: Options such as WithLockStrategy
+//
+// Returns:
+//   - A pointer to the initialized SPSC[T]
+//
+// The safety requirements of Make apply unchanged.
+func MakeSPSC[T any](ptr unsafe.Pointer, n int, opts ...Option) *SPSC[T] {
+	o := newOptions(opts)
+	c := (*SPSC[T])(ptr)
+	c.cap = int64(n)
+	c.mask = ringMask(c.cap)
+	c.head = 0
+	c.tail = 0
+	c.headCache = 0
+	c.tailCache = 0
+	c.notifier.init(o.strategy)
+	c.initHeader()
+	return c
+}
+
+// index returns the slot holding position pos, as Channel[T].index does.
+func (c *SPSC[T]) index(pos int64) int64 {
+	if c.mask >= 0 {
+		return pos & c.mask
+	}
+	return pos % c.cap
+}
+
+// buffer returns a slice view of the internal ring buffer.
+func (c *SPSC[T]) buffer() []T {
+	if c == nil {
+		return nil
+	}
+	structSize := unsafe.Sizeof(SPSC[T]{})
+	alignedOffset := alignUp(int(structSize), int(unsafe.Alignof(*new(T))))
+	addr := unsafe.Pointer(uintptr(unsafe.Pointer(c)) + uintptr(alignedOffset))
+	return unsafe.Slice((*T)(addr), c.cap)
+}
+
+// Push attempts to add a value to the channel without blocking.
+// It must only be called from the producer goroutine.
+//
+// Returns:
+//   - true if the value was successfully added
+//   - false if the channel is full or closed
+func (c *SPSC[T]) Push(val T) (ok bool) {
+	if c == nil {
+		return
+	}
+	return c.push(val) == nil
+}
+
+// TryPush is like Push but reports why the value could not be added.
+//
+// Returns:
+//   - nil if the value was successfully added
+//   - ErrFull if the channel is full
+//   - ErrClosed if the channel has been closed
+func (c *SPSC[T]) TryPush(val T) error {
+	if c == nil {
+		return ErrFull
+	}
+	return c.push(val)
+}
+
+// push implements TryPush.
+func (c *SPSC[T]) push(val T) error {
+	tail := atomic.LoadInt64(&c.tail)
+	if tail&closedBit != 0 {
+		return ErrClosed
+	}
+	if tail-c.headCache >= c.cap {
+		c.headCache = atomic.LoadInt64(&c.head)
+		if tail-c.headCache >= c.cap {
+			return ErrFull
+		}
+	}
+	c.buffer()[c.index(tail)] = val
+	// Only Close competes for tail, so a failed swap means the channel closed.
+	if !atomic.CompareAndSwapInt64(&c.tail, tail, tail+1) {
+		return ErrClosed
+	}
+	c.notify()
+	return nil
+}
+
+// PushN attempts to add the values of src to the channel, in order, copying
+// them into the ring with at most two copy calls.
+// It must only be called from the producer goroutine.
+//
+// Returns:
+//   - The number of leading values of src that were added; fewer than len(src)
+//     if the channel filled up, and 0 if it was full or closed
+func (c *SPSC[T]) PushN(src []T) int {
+	if c == nil || len(src) == 0 {
+		return 0
+	}
+	tail := atomic.LoadInt64(&c.tail)
+	if tail&closedBit != 0 {
+		return 0
+	}
+	if tail-c.headCache+int64(len(src)) > c.cap {
+		c.headCache = atomic.LoadInt64(&c.head)
+	}
+	k := min(int64(len(src)), c.cap-(tail-c.headCache))
+	if k <= 0 {
+		return 0
+	}
+	copyToRing(c.buffer(), int(c.index(tail)), src[:k])
+	if !atomic.CompareAndSwapInt64(&c.tail, tail, tail+k) {
+		return 0
+	}
+	c.notify()
+	return int(k)
+}
+
+// PushWait adds a value to the channel, waiting for free space if it is full.
+// It must only be called from the producer goroutine.
+//
+// Returns:
+//   - nil if the value was added
+//   - ErrClosed if the channel is or becomes closed while waiting
+//   - ctx.Err() if ctx was done before space became available
+func (c *SPSC[T]) PushWait(ctx context.Context, val T) error {
+	if c == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := c.push(val); err != ErrFull {
+		return err
+	}
+	return pushWait(ctx, c, val)
+}
+
+// Pop attempts to remove and return a value from the channel without
+// blocking. It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T if empty
+//   - ok: true if a value was successfully removed, false if the channel was empty
+func (c *SPSC[T]) Pop() (v T, ok bool) {
+	if c == nil {
+		return
+	}
+	v, err := c.pop()
+	ok = err == nil
+	return
+}
+
+// TryPop is like Pop but distinguishes a channel that is merely empty from one
+// that has been closed and drained.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrEmpty if the channel is empty but still open,
+//     or ErrClosed if the channel is closed and no values remain
+func (c *SPSC[T]) TryPop() (v T, err error) {
+	if c == nil {
+		err = ErrEmpty
+		return
+	}
+	return c.pop()
+}
+
+// pop implements TryPop.
+func (c *SPSC[T]) pop() (v T, err error) {
+	head := atomic.LoadInt64(&c.head)
+	if head == c.tailCache {
+		tail := atomic.LoadInt64(&c.tail)
+		c.tailCache = tail &^ closedBit
+		if head == c.tailCache {
+			if tail&closedBit != 0 {
+				err = ErrClosed
+			} else {
+				err = ErrEmpty
+			}
+			return
+		}
+	}
+	v = c.buffer()[c.index(head)]
+	atomic.StoreInt64(&c.head, head+1)
+	c.notify()
+	return
+}
+
+// PopN attempts to remove values from the channel into dst, in order, copying
+// them out of the ring with at most two copy calls.
+// It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - The number of values written to the front of dst; fewer than len(dst)
+//     if the channel ran empty, and 0 if it was empty
+func (c *SPSC[T]) PopN(dst []T) int {
+	if c == nil || len(dst) == 0 {
+		return 0
+	}
+	head := atomic.LoadInt64(&c.head)
+	if c.tailCache-head < int64(len(dst)) {
+		c.tailCache = atomic.LoadInt64(&c.tail) &^ closedBit
+	}
+	k := min(int64(len(dst)), c.tailCache-head)
+	if k <= 0 {
+		return 0
+	}
+	copyFromRing(dst[:k], c.buffer(), int(c.index(head)))
+	atomic.StoreInt64(&c.head, head+k)
+	c.notify()
+	return int(k)
+}
+
+// PopWait removes and returns a value from the channel, waiting for one to
+// arrive if it is empty. It must only be called from the consumer goroutine.
+//
+// Returns:
+//   - v: The value removed from the channel, or zero value of T on error
+//   - err: nil on success, ErrClosed if the channel is closed and drained,
+//     or ctx.Err() if ctx was done before a value arrived
+func (c *SPSC[T]) PopWait(ctx context.Context) (v T, err error) {
+	if c == nil {
+		<-ctx.Done()
+		err = ctx.Err()
+		return
+	}
+	if v, err = c.pop(); err != ErrEmpty {
+		return
+	}
+	return popWait(ctx, c)
+}
+
+// Close marks the channel as closed, with the same semantics as
+// Channel[T].Close.
+//
+// Returns:
+//   - nil if the channel was closed by this call
+//   - ErrClosed if the channel had already been closed
+func (c *SPSC[T]) Close() error {
+	if c == nil {
+		return ErrClosed
+	}
+	for {
+		tail := atomic.LoadInt64(&c.tail)
+		if tail&closedBit != 0 {
+			return ErrClosed
+		}
+		if atomic.CompareAndSwapInt64(&c.tail, tail, tail|closedBit) {
+			c.wake()
+			return nil
+		}
+	}
+}
+
+// Closed reports whether Close has been called on the channel.
+func (c *SPSC[T]) Closed() bool {
+	if c == nil {
+		return false
+	}
+	return atomic.LoadInt64(&c.tail)&closedBit != 0
+}
+
+// Len returns a snapshot of the number of elements stored in the channel.
+func (c *SPSC[T]) Len() int {
+	if c == nil {
+		return 0
+	}
+	head := atomic.LoadInt64(&c.head)
+	tail := atomic.LoadInt64(&c.tail) &^ closedBit
+	return int(min(tail-head, c.cap))
+}
+
+// Cap returns the maximum capacity of the channel.
+func (c *SPSC[T]) Cap() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.cap)
+}
@@ -0,0 +1,142 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestSPSCFull(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 5
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, n)
+	assert.NotNil(ch)
+	assert.Equal(n, ch.Cap())
+	assert.Equal(0, ch.Len())
+
+	for round := range 3 {
+		for i := range n {
+			assert.True(ch.Push(round*n + i))
+		}
+		assert.False(ch.Push(-1))
+		assert.ErrorIs(ch.TryPush(-1), xxchan.ErrFull)
+		assert.Equal(n, ch.Len())
+		for i := range n {
+			val, ok := ch.Pop()
+			assert.True(ok)
+			assert.Equal(round*n+i, val)
+		}
+		_, err := ch.TryPop()
+		assert.ErrorIs(err, xxchan.ErrEmpty)
+	}
+
+	assert.True(ch.Push(1))
+	assert.NoError(ch.Close())
+	assert.True(ch.Closed())
+	assert.ErrorIs(ch.TryPush(2), xxchan.ErrClosed)
+	val, err := ch.TryPop()
+	assert.NoError(err)
+	assert.Equal(1, val)
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestSPSCZeroCapacity(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](0)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, 0)
+	attached, err := xxchan.AttachSPSC[int](ptr)
+	assert.NoError(err)
+	assert.Equal(0, attached.Cap())
+
+	assert.ErrorIs(ch.TryPush(1), xxchan.ErrFull)
+	assert.Equal(0, ch.PushN([]int{1}))
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrEmpty)
+	assert.Equal(0, ch.PopN(make([]int, 1)))
+
+	assert.NoError(ch.Close())
+	_, err = ch.TryPop()
+	assert.ErrorIs(err, xxchan.ErrClosed)
+}
+
+func TestSPSCConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	total := 100000
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, n)
+	ctx := context.Background()
+
+	go func() {
+		for i := range total {
+			if err := ch.PushWait(ctx, i); err != nil {
+				panic(err)
+			}
+		}
+		_ = ch.Close()
+	}()
+
+	for i := 0; ; i++ {
+		val, err := ch.PopWait(ctx)
+		if err != nil {
+			assert.ErrorIs(err, xxchan.ErrClosed)
+			assert.Equal(total, i)
+			break
+		}
+		assert.Equal(i, val)
+	}
+}
+
+func TestSPSCBatch(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 5
+	ptr := alloc(uint(xxchan.SizeofSPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeSPSC[int](ptr, n)
+
+	assert.Equal(3, ch.PushN([]int{-1, -2, -3}))
+	assert.Equal(3, ch.PopN(make([]int, 3)))
+
+	assert.Equal(n, ch.PushN([]int{0, 1, 2, 3, 4, 5}))
+	assert.Equal(0, ch.PushN([]int{5}))
+	dst := make([]int, 8)
+	assert.Equal(n, ch.PopN(dst))
+	assert.Equal([]int{0, 1, 2, 3, 4}, dst[:n])
+	assert.Equal(0, ch.PopN(dst))
+}
@@ -0,0 +1,190 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// counters holds the operation counters kept in a channel's header. Successful
+// pushes and pops are not counted separately: they are the free-running tail
+// and head positions themselves.
+type counters struct {
+	full       int64 // Pushes that found the channel full
+	empty      int64 // Pops that found the channel empty
+	contention int64 // Compare-and-swap retries caused by other goroutines
+	highWater  int64 // Largest length observed after a push
+}
+
+// Stats is a snapshot of a channel's operation counters, as returned by
+// Channel.Stats.
+//
+// The counters live in the channel's memory block, so they cover every
+// goroutine, and every process, that has used the channel since Make.
+type Stats struct {
+	Pushes     uint64 `json:"pushes"`     // Values added
+	Pops       uint64 `json:"pops"`       // Values removed, including values evicted by an overflow policy
+	Full       uint64 `json:"full"`       // Push attempts that found the channel full
+	Empty      uint64 `json:"empty"`      // Pop attempts that found the channel empty
+	Contention uint64 `json:"contention"` // Compare-and-swap retries caused by concurrent operations
+	HighWater  int    `json:"high_water"` // Largest number of values held at once
+	Len        int    `json:"len"`        // Number of values held when the snapshot was taken
+	Cap        int    `json:"cap"`        // Capacity of the channel
+}
+
+// count increments a counter.
+func count(n *int64) {
+	atomic.AddInt64(n, 1)
+}
+
+// observe raises the high-water mark to the length the channel had once the
+// enqueue at position tail completed.
+//
+// Loading head on every push would pull in the cache line that consumers keep
+// writing. Instead, observe first checks the slot of position tail-hw, where
+// hw is the current mark: the length can only exceed hw if that position has
+// not been consumed yet, that is while the ring is fuller than it has ever
+// been, and only then is head loaded.
+func (c *Channel[T]) observe(tail int64) {
+	stats := c.counters()
+	hw := atomic.LoadInt64(&stats.highWater)
+	if hw >= c.cap || tail < hw {
+		return
+	}
+	if pos := tail - hw; atomic.LoadInt64(&c.seqs()[c.index(pos)])&^abandonedBit >= 2*(pos+c.cap) {
+		return
+	}
+	n := tail + 1 - atomic.LoadInt64(c.headp())
+	for {
+		hw := atomic.LoadInt64(&stats.highWater)
+		if n <= hw || atomic.CompareAndSwapInt64(&stats.highWater, hw, n) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the channel's operation counters.
+//
+// Counting uses atomic instructions on the channel header and never
+// allocates. The counters are read one at a time, so a snapshot taken while
+// other goroutines use the channel is only approximately consistent.
+func (c *Channel[T]) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	head := atomic.LoadInt64(c.headp())
+	tail := atomic.LoadInt64(c.tailp()) &^ closedBit
+	stats := c.counters()
+	return Stats{
+		Pushes:     uint64(tail),
+		Pops:       uint64(head),
+		Full:       uint64(atomic.LoadInt64(&stats.full)),
+		Empty:      uint64(atomic.LoadInt64(&stats.empty)),
+		Contention: uint64(atomic.LoadInt64(&stats.contention)),
+		HighWater:  int(atomic.LoadInt64(&stats.highWater)),
+		Len:        int(min(max(tail-head, 0), c.cap)),
+		Cap:        int(c.cap),
+	}
+}
+
+// StatsVar reports a channel's Stats as JSON.
+//
+// It satisfies expvar.Var, so a channel can be published without this package
+// importing expvar, which would register its HTTP handler in every program:
+//
+//	expvar.Publish("jobs", ch.StatsVar())
+type StatsVar func() Stats
+
+// String returns the current Stats encoded as JSON.
+func (f StatsVar) String() string {
+	b, err := json.Marshal(f())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// StatsVar returns an expvar.Var reporting the channel's Stats.
+func (c *Channel[T]) StatsVar() StatsVar {
+	return c.Stats
+}
+
+// Stats returns a snapshot of the channel's operation counters; see
+// Channel.Stats.
+func (c *MPSC[T]) Stats() Stats {
+	return c.mpmc().Stats()
+}
+
+// StatsVar returns an expvar.Var reporting the channel's Stats.
+func (c *MPSC[T]) StatsVar() StatsVar {
+	return c.Stats
+}
+
+// promMetrics describes the metric families written by WritePrometheus.
+var promMetrics = []struct {
+	name, typ, help string
+	value           func(Stats) uint64
+}{
+	{"xxchan_pushes_total", "counter", "Values added to the channel.", func(s Stats) uint64 { return s.Pushes }},
+	{"xxchan_pops_total", "counter", "Values removed from the channel.", func(s Stats) uint64 { return s.Pops }},
+	{"xxchan_full_total", "counter", "Push attempts that found the channel full.", func(s Stats) uint64 { return s.Full }},
+	{"xxchan_empty_total", "counter", "Pop attempts that found the channel empty.", func(s Stats) uint64 { return s.Empty }},
+	{"xxchan_contention_total", "counter", "Compare-and-swap retries caused by concurrent operations.", func(s Stats) uint64 { return s.Contention }},
+	{"xxchan_high_water", "gauge", "Largest number of values held at once.", func(s Stats) uint64 { return uint64(s.HighWater) }},
+	{"xxchan_len", "gauge", "Number of values currently held.", func(s Stats) uint64 { return uint64(s.Len) }},
+	{"xxchan_cap", "gauge", "Capacity of the channel.", func(s Stats) uint64 { return uint64(s.Cap) }},
+}
+
+// labelEscaper escapes label values for the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes channel statistics in the Prometheus text exposition
+// format, one sample per channel labelled with channel="<name>".
+//
+// Each metric family is written once with all channels' samples, sorted by
+// name, so the output can be served as is from a /metrics handler.
+//
+// Parameters:
+//   - w: Destination for the metrics
+//   - stats: Snapshots keyed by channel name
+//
+// Returns:
+//   - The first error returned by w
+func WritePrometheus(w io.Writer, stats map[string]Stats) error {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, m := range promMetrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "%s{channel=\"%s\"} %d\n", m.name, labelEscaper.Replace(name), m.value(stats[name])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
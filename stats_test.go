@@ -0,0 +1,168 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	assert.Equal(xxchan.Stats{Cap: n}, ch.Stats())
+
+	_, ok := ch.Pop()
+	assert.False(ok)
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+	assert.True(ch.Push(4))
+	assert.False(ch.Push(5))
+	assert.Equal(0, ch.PushN([]int{5}))
+	_, ok = ch.Pop()
+	assert.True(ok)
+	assert.Equal(3, ch.PopN(make([]int, 4)))
+	assert.Equal(0, ch.PopN(make([]int, 4)))
+
+	assert.Equal(xxchan.Stats{
+		Pushes:    4,
+		Pops:      4,
+		Full:      2,
+		Empty:     2,
+		HighWater: 4,
+		Len:       0,
+		Cap:       n,
+	}, ch.Stats())
+
+	// Make resets the counters kept in the block.
+	ch = xxchan.Make[int](ptr, n)
+	assert.Equal(xxchan.Stats{Cap: n}, ch.Stats())
+}
+
+func TestStatsHighWater(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	// The mark only moves when the length passes it, across laps of the ring
+	// and with values popped in between.
+	ch := xxchan.Make[int](ptr, n)
+	for range 2 * n {
+		assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+		assert.Equal(3, ch.PopN(make([]int, 3)))
+		assert.Equal(3, ch.Stats().HighWater)
+	}
+	assert.True(ch.Push(0))
+	for range 2 * n {
+		assert.True(ch.Push(1))
+		assert.Equal(2, ch.Len())
+		_, ok := ch.Pop()
+		assert.True(ok)
+		assert.Equal(3, ch.Stats().HighWater)
+	}
+	assert.Equal(3, ch.PushN([]int{1, 2, 3}))
+	assert.Equal(4, ch.Stats().HighWater)
+	assert.Equal(4, ch.PushN([]int{1, 2, 3, 4}))
+	assert.Equal(n, ch.Stats().HighWater)
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 8
+	ptr := alloc(uint(xxchan.SizeofMPSC[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.MakeMPSC[int](ptr, n)
+
+	producers, perProducer := 4, 1000
+	var wg sync.WaitGroup
+	for range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				for !ch.Push(i) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	for got := 0; got < producers*perProducer; {
+		if _, ok := ch.Pop(); ok {
+			got++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+
+	s := ch.Stats()
+	assert.Equal(uint64(producers*perProducer), s.Pushes)
+	assert.Equal(uint64(producers*perProducer), s.Pops)
+	assert.LessOrEqual(s.HighWater, n)
+	assert.Positive(s.HighWater)
+	assert.Equal(0, s.Len)
+}
+
+func TestStatsExport(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	n := 4
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n)
+	assert.True(ch.Push(1))
+
+	// Publishing would register the name for the whole process, and fail
+	// when the test runs again; using the value as an expvar.Var suffices.
+	var v expvar.Var = ch.StatsVar()
+	var s xxchan.Stats
+	assert.NoError(json.Unmarshal([]byte(v.String()), &s))
+	assert.Equal(ch.Stats(), s)
+
+	var b strings.Builder
+	assert.NoError(xxchan.WritePrometheus(&b, map[string]xxchan.Stats{
+		"jobs":     ch.Stats(),
+		`odd"name`: {Cap: 2},
+	}))
+	out := b.String()
+	assert.Contains(out, "# TYPE xxchan_pushes_total counter\n"+
+		"xxchan_pushes_total{channel=\"jobs\"} 1\n"+
+		"xxchan_pushes_total{channel=\"odd\\\"name\"} 0\n")
+	assert.Contains(out, "# TYPE xxchan_cap gauge\n")
+	assert.Equal(1, strings.Count(out, "# HELP xxchan_len "))
+}
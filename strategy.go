@@ -0,0 +1,134 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+	_ "unsafe" // for go:linkname
+)
+
+// LockStrategy selects how goroutines back off and wait on a channel.
+//
+// The rings are lock-free, so no goroutine ever waits for a lock holder. The
+// strategy governs the two places where a goroutine still has to wait: retrying
+// after another goroutine won a compare-and-swap on the same position, and
+// waiting in PushWait or PopWait for the channel to change. It is chosen when
+// the channel is created, with WithLockStrategy, and stored in the channel's
+// header.
+type LockStrategy uint8
+
+const (
+	// LockAdaptive retries compare-and-swap races at once and, when waiting,
+	// spins briefly before yielding with runtime.Gosched. It is the default.
+	LockAdaptive LockStrategy = iota
+
+	// LockSpin busy-waits with the processor's spin-loop hint (PAUSE on
+	// x86), never giving up the thread. It has the lowest wake-up latency
+	// but burns a CPU per waiting goroutine, so it only suits channels whose
+	// goroutines each have a processor of their own; otherwise a spinning
+	// goroutine holds up the one it waits for until the scheduler preempts it.
+	LockSpin
+
+	// LockYield calls runtime.Gosched on every retry and every wait
+	// iteration, letting other goroutines run on the same processor.
+	LockYield
+
+	// LockBackoff spins with exponentially growing pauses after a lost race,
+	// and sleeps with exponentially growing durations, up to a millisecond,
+	// when waiting.
+	LockBackoff
+
+	// LockFutex sleeps in the kernel on the channel's sequence word with the
+	// Linux futex system call, and is woken by the goroutine that changes the
+	// channel. Waiting goroutines use no CPU at all, at the cost of a system
+	// call per wake-up. On other systems it behaves like LockAdaptive.
+	LockFutex
+
+	// LockFutexShared is LockFutex for channels in memory shared between
+	// processes, such as those made by Create. It uses the futex operations
+	// the kernel keys by the page holding the sequence word rather than by
+	// its address in one process, so a process pushing to the channel wakes
+	// consumers sleeping in another, and a process popping wakes blocked
+	// producers. The word and the waiter count live in the channel's block,
+	// so no process keeps any state of its own. Private futexes are cheaper,
+	// so LockFutex remains the better choice within one process. On other
+	// systems it behaves like LockAdaptive.
+	LockFutexShared
+)
+
+const (
+	// spinCycles is the number of spin-loop hints issued per spin.
+	spinCycles = 30
+
+	// minBackoff and maxBackoff bound the sleeps of LockBackoff.
+	minBackoff = time.Microsecond
+	maxBackoff = time.Millisecond
+
+	// futexPoll bounds each futex sleep when the wait can be canceled, since
+	// the kernel cannot observe a context.
+	futexPoll = time.Millisecond
+)
+
+// procyield executes cycles spin-loop hints. It is the runtime's own
+// primitive for active spinning.
+//
+//go:linkname procyield runtime.procyield
+func procyield(cycles uint32)
+
+// String returns the name of the strategy.
+func (s LockStrategy) String() string {
+	switch s {
+	case LockAdaptive:
+		return "adaptive"
+	case LockSpin:
+		return "spin"
+	case LockYield:
+		return "yield"
+	case LockBackoff:
+		return "backoff"
+	case LockFutex:
+		return "futex"
+	case LockFutexShared:
+		return "futex-shared"
+	}
+	return fmt.Sprintf("LockStrategy(%d)", uint8(s))
+}
+
+// WithLockStrategy sets how goroutines back off and wait on the channel.
+func WithLockStrategy(strategy LockStrategy) Option {
+	return func(o *options) { o.strategy = strategy }
+}
+
+// backoff pauses after the attempt-th consecutive compare-and-swap race lost
+// to another goroutine.
+func (n *notifier) backoff(attempt int) {
+	switch LockStrategy(n.strategy) {
+	case LockSpin:
+		procyield(spinCycles)
+	case LockYield:
+		runtime.Gosched()
+	case LockBackoff:
+		if attempt < 8 {
+			procyield(spinCycles << attempt)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
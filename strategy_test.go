@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xxchan_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.yuchanns.xyz/xxchan"
+)
+
+var lockStrategies = []xxchan.LockStrategy{
+	xxchan.LockAdaptive,
+	xxchan.LockSpin,
+	xxchan.LockYield,
+	xxchan.LockBackoff,
+	xxchan.LockFutex,
+	xxchan.LockFutexShared,
+}
+
+func TestLockStrategy(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range lockStrategies {
+		t.Run(strategy.String(), func(t *testing.T) {
+			t.Parallel()
+			assert := require.New(t)
+
+			producers, perProducer := 4, 500
+			if strategy == xxchan.LockSpin && runtime.GOMAXPROCS(0) <= producers {
+				t.Skip("LockSpin needs a processor per goroutine")
+			}
+
+			n := 4
+			ptr := alloc(uint(xxchan.Sizeof[int](n)))
+			t.Cleanup(func() { free(ptr) })
+
+			ch := xxchan.Make[int](ptr, n, xxchan.WithLockStrategy(strategy))
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			for range producers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 1; i <= perProducer; i++ {
+						assert.NoError(ch.PushWait(ctx, i))
+					}
+				}()
+			}
+			go func() {
+				wg.Wait()
+				_ = ch.Close()
+			}()
+
+			sum := 0
+			for {
+				v, err := ch.PopWait(ctx)
+				if err != nil {
+					assert.ErrorIs(err, xxchan.ErrClosed)
+					break
+				}
+				sum += v
+			}
+			assert.Equal(producers*perProducer*(perProducer+1)/2, sum)
+		})
+	}
+}
+
+func TestLockStrategyCanceled(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range lockStrategies {
+		t.Run(strategy.String(), func(t *testing.T) {
+			t.Parallel()
+			assert := require.New(t)
+
+			ptr := alloc(uint(xxchan.SizeofSPSC[int](1)))
+			t.Cleanup(func() { free(ptr) })
+
+			ch := xxchan.MakeSPSC[int](ptr, 1, xxchan.WithLockStrategy(strategy))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err := ch.PopWait(ctx)
+			assert.ErrorIs(err, context.DeadlineExceeded)
+		})
+	}
+}
+
+func TestLockStrategyAllocs(t *testing.T) {
+	assert := require.New(t)
+
+	n := 1
+	ptr := alloc(uint(xxchan.Sizeof[int](n)))
+	t.Cleanup(func() { free(ptr) })
+
+	ch := xxchan.Make[int](ptr, n, xxchan.WithLockStrategy(xxchan.LockFutex))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The consumer keeps waking the producer, which sleeps in the kernel
+	// with a timeout because ctx can be canceled.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := ch.PopWait(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := range 4 {
+			_ = ch.PushWait(ctx, i)
+		}
+	})
+	assert.NoError(ch.Close())
+	<-done
+	assert.Equal(0.0, allocs)
+}